@@ -0,0 +1,81 @@
+// Copyright 2018 wyDay, LLC. All rights reserved.
+
+package turboactivate // import "golang.wyday.com/turboactivate"
+
+import "errors"
+
+// Backend is the set of TurboActivate library functions (the TA_* functions
+// in TurboActivate.h) that the TurboActivate object dispatches to.
+//
+// The default backend calls into the native TurboActivate library through cgo.
+// Other implementations (for example the in-memory backend used in tests) can
+// be passed to NewTurboActivateWithBackend.
+//
+// Every function returns the raw HRESULT from the library. The TurboActivate
+// object takes care of converting them into errors.
+type Backend interface {
+	// PDetsFromPath loads the "TurboActivate.dat" file from a path.
+	PDetsFromPath(filename string) HRESULT
+
+	// PDetsFromByteArray loads the contents of the "TurboActivate.dat" file.
+	PDetsFromByteArray(data []byte) HRESULT
+
+	// GetHandle gets the handle for the VersionGUID. Returns 0 on failure.
+	GetHandle(versionGUID string) uint32
+
+	// GetVersion gets the version of the TurboActivate library.
+	GetVersion() (major uint32, minor uint32, build uint32, revision uint32, ret HRESULT)
+
+	// Cleanup releases the resources of the library. Every handle is
+	// invalid afterwards.
+	Cleanup() HRESULT
+
+	Activate(handle uint32, extraData string) HRESULT
+	ActivationRequestToFile(handle uint32, filename string, extraData string) HRESULT
+	ActivateFromFile(handle uint32, filename string) HRESULT
+	CheckAndSavePKey(handle uint32, productKey string, flags TAFlags) HRESULT
+	Deactivate(handle uint32, eraseProductKey bool) HRESULT
+	DeactivationRequestToFile(handle uint32, filename string, eraseProductKey bool) HRESULT
+	GetExtraData(handle uint32) (string, HRESULT)
+	GetFeatureValue(handle uint32, featureName string) (string, HRESULT)
+	GetPKey(handle uint32) (string, HRESULT)
+	IsActivated(handle uint32) HRESULT
+	IsDateValid(handle uint32, dateTime string, flags TADateCheckFlags) HRESULT
+	IsGenuine(handle uint32) HRESULT
+	IsGenuineEx(handle uint32, daysBetweenChecks uint32, graceDaysOnInetErr uint32, skipOffline bool, offlineShowInetErr bool) HRESULT
+	GenuineDays(handle uint32, daysBetweenChecks uint32, graceDaysOnInetErr uint32) (daysRemaining uint32, inGracePeriod bool, ret HRESULT)
+	IsProductKeyValid(handle uint32) HRESULT
+	SetCustomProxy(proxy string) HRESULT
+	TrialDaysRemaining(handle uint32, flags TAFlags) (uint32, HRESULT)
+	UseTrial(handle uint32, flags TAFlags, extraData string) HRESULT
+	UseTrialVerifiedRequest(handle uint32, filename string, extraData string) HRESULT
+	UseTrialVerifiedFromFile(handle uint32, filename string, flags TAFlags) HRESULT
+	ExtendTrial(handle uint32, flags TAFlags, trialExtension string) HRESULT
+	SetCustomActDataPath(handle uint32, directory string) HRESULT
+	BlackListKeys(handle uint32, keys []string) HRESULT
+
+	// SetTrialCallback sets the function called when the verified trial
+	// expires (status 0x00, TA_CB_EXPIRED) or expires because the trial data
+	// was tampered with (status 0x01, TA_CB_EXPIRED_FRAUD). A nil callback
+	// stops the notifications. The callback can be called on any thread
+	// while another function runs, so it must return quickly and must not
+	// call into the backend.
+	SetTrialCallback(handle uint32, callback func(status uint32)) HRESULT
+}
+
+// ErrNoBackend is returned by NewTurboActivate when the package was built
+// without the native TurboActivate library (that is, with cgo disabled or
+// with the "turboactivate_nonative" build tag).
+var ErrNoBackend = errors.New("TurboActivate was built without the native library. Use NewTurboActivateWithBackend instead")
+
+// defaultBackend is set to the native backend when it's built in.
+var defaultBackend Backend
+
+// defaultBackendErr is returned when defaultBackend is nil.
+var defaultBackendErr = ErrNoBackend
+
+// DefaultBackend returns the backend that calls into the native TurboActivate
+// library, or nil if the package was built without it.
+func DefaultBackend() Backend {
+	return defaultBackend
+}
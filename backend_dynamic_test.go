@@ -0,0 +1,41 @@
+// Copyright 2018 wyDay, LLC. All rights reserved.
+
+// +build turboactivate_dynamic
+
+package turboactivate_test
+
+import (
+	"errors"
+	"testing"
+
+	"golang.wyday.com/turboactivate"
+)
+
+func TestDefaultBackend(t *testing.T) {
+	var lib = turboactivate.LoadedLibrary()
+
+	if lib == nil {
+		// the library wasn't found when the program started
+		if b := turboactivate.DefaultBackend(); b != nil {
+			t.Fatalf("DefaultBackend() = %T without a loaded library; want nil", b)
+		}
+
+		if _, err := turboactivate.NewTurboActivate(testGUID, ""); !errors.Is(err, turboactivate.ErrLibraryNotFound) || errors.Is(err, turboactivate.ErrNoBackend) {
+			t.Errorf("NewTurboActivate() = %v; want ErrLibraryNotFound", err)
+		}
+
+		if _, err := turboactivate.New(testGUID); !errors.Is(err, turboactivate.ErrLibraryNotFound) {
+			t.Errorf("New() = %v; want ErrLibraryNotFound", err)
+		}
+
+		return
+	}
+
+	if turboactivate.DefaultBackend() == nil {
+		t.Fatalf("DefaultBackend() = nil; want the library loaded from %s", lib.Path())
+	}
+
+	if _, _, _, _, err := turboactivate.Version(); err != nil {
+		t.Errorf("Version() = %v", err)
+	}
+}
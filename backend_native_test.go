@@ -0,0 +1,26 @@
+// Copyright 2018 wyDay, LLC. All rights reserved.
+
+// +build cgo,!turboactivate_nonative,!turboactivate_dynamic
+
+package turboactivate_test
+
+import (
+	"testing"
+
+	"golang.wyday.com/turboactivate"
+)
+
+func TestDefaultBackend(t *testing.T) {
+
+	if turboactivate.DefaultBackend() == nil {
+		t.Fatal("DefaultBackend() = nil; want the linked library")
+	}
+
+	if turboactivate.LoadedLibrary() != nil {
+		t.Error("LoadedLibrary() isn't nil when the library is linked")
+	}
+
+	if _, _, _, _, err := turboactivate.Version(); err != nil {
+		t.Errorf("Version() = %v", err)
+	}
+}
@@ -0,0 +1,46 @@
+// Copyright 2018 wyDay, LLC. All rights reserved.
+
+// +build turboactivate_nonative !cgo
+// +build !turboactivate_dynamic
+
+package turboactivate_test
+
+import (
+	"errors"
+	"testing"
+
+	"golang.wyday.com/turboactivate"
+	"golang.wyday.com/turboactivate/fake"
+)
+
+func TestDefaultBackend(t *testing.T) {
+
+	if b := turboactivate.DefaultBackend(); b != nil {
+		t.Fatalf("DefaultBackend() = %T; want nil without the native library", b)
+	}
+
+	if _, err := turboactivate.NewTurboActivate(testGUID, ""); !errors.Is(err, turboactivate.ErrNoBackend) {
+		t.Errorf("NewTurboActivate() = %v; want ErrNoBackend", err)
+	}
+
+	if _, err := turboactivate.New(testGUID); !errors.Is(err, turboactivate.ErrNoBackend) {
+		t.Errorf("New() = %v; want ErrNoBackend", err)
+	}
+
+	if _, _, _, _, err := turboactivate.Version(); !errors.Is(err, turboactivate.ErrNoBackend) {
+		t.Errorf("Version() = %v; want ErrNoBackend", err)
+	}
+
+	if err := turboactivate.Cleanup(); !errors.Is(err, turboactivate.ErrNoBackend) {
+		t.Errorf("Cleanup() = %v; want ErrNoBackend", err)
+	}
+
+	// a backend can still be passed in
+	ta, err := turboactivate.NewTurboActivateWithBackend(fake.New(), testGUID, "")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ta.Close()
+}
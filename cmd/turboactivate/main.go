@@ -0,0 +1,539 @@
+// Copyright 2018 wyDay, LLC. All rights reserved.
+
+// Command turboactivate checks and manages the TurboActivate activation of a
+// product on this computer.
+//
+// Usage:
+//
+//	turboactivate [global flags] <command> [arguments]
+//
+// The global flags are:
+//
+//	-guid string    the VersionGUID of the product (or $TURBOACTIVATE_GUID)
+//	-dat string     path to TurboActivate.dat (or $TURBOACTIVATE_DAT)
+//	-user           store the activation / trial per-user instead of system-wide
+//	-unverified     use unverified trials instead of verified trials
+//	-json           print the results as JSON
+//
+// The commands are:
+//
+//	status [-features a,b] [-offline]
+//	                            show the activation and trial status
+//	activate <key> [extra]      save the product key and activate it
+//	deactivate [-erase]         deactivate (and optionally erase the product key)
+//	genuine [-days N] [-grace N] [-now]
+//	                            check whether the activation is genuine
+//	trial start [extra]         start (or validate) the trial
+//	trial days                  show the trial days remaining
+//	trial extend <extension>    extend the trial
+//	feature get <name>          show the value of a custom license field
+//	extra-data                  show the extra data passed when activating
+//	offline request <file> [extra]
+//	                            write the offline activation request file
+//	offline apply <file>        activate from the offline activation response file
+//	offline deactivate <file> [-erase]
+//	                            deactivate and write the deactivation request file
+//
+// The exit code is 0 on success, 2 for invalid usage, 3 when the product is not
+// genuine (or not activated), 4 when it's not genuine because it's running in a
+// VM, 5 when the servers couldn't be reached during a genuine check, and
+// 100 + HRESULT when the TurboActivate library returns an error (for example
+// 106 for TA_E_REVOKED). The HRESULTs above 154 don't fit in an exit code, so
+// they all exit with 255. Any other error exits with 1.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"golang.wyday.com/turboactivate"
+)
+
+const (
+	exitOK             = 0
+	exitError          = 1
+	exitUsage          = 2
+	exitNotGenuine     = 3
+	exitNotGenuineInVM = 4
+	exitInternetError  = 5
+	exitHRESULTBase    = 100
+	exitHRESULTOther   = 255
+)
+
+// usageError is returned for invalid command line arguments.
+type usageError string
+
+func (e usageError) Error() string {
+	return string(e)
+}
+
+// result is the output of a command.
+type result map[string]interface{}
+
+type cli struct {
+	ta       *turboactivate.TurboActivate
+	flags    turboactivate.TAFlags
+	jsonOut  bool
+	out      io.Writer
+	exitCode int
+}
+
+// newTurboActivate creates the TurboActivate instance (tests replace it to use a fake backend).
+var newTurboActivate = turboactivate.New
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout io.Writer, stderr io.Writer) int {
+	fs := flag.NewFlagSet("turboactivate", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	guid := fs.String("guid", os.Getenv("TURBOACTIVATE_GUID"), "the VersionGUID of the product")
+	dat := fs.String("dat", os.Getenv("TURBOACTIVATE_DAT"), "path to TurboActivate.dat")
+	user := fs.Bool("user", false, "store the activation / trial per-user instead of system-wide")
+	unverified := fs.Bool("unverified", false, "use unverified trials instead of verified trials")
+	jsonOut := fs.Bool("json", false, "print the results as JSON")
+
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: turboactivate [global flags] <command> [arguments]")
+		fmt.Fprintln(stderr, "commands: status, activate, deactivate, genuine, trial, feature, extra-data, offline")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	if *guid == "" {
+		fmt.Fprintln(stderr, "turboactivate: -guid (or $TURBOACTIVATE_GUID) is required")
+		return exitUsage
+	}
+
+	var flags = turboactivate.TASystem
+
+	if *user {
+		flags = turboactivate.TAUser
+	}
+
+	if *unverified {
+		flags |= turboactivate.TAUnverifiedTrial
+	} else {
+		flags |= turboactivate.TAVerifiedTrial
+	}
+
+	var opts = []turboactivate.Option{turboactivate.WithDefaultFlags(flags)}
+
+	if *dat != "" {
+		opts = append(opts, turboactivate.WithProductDetailsFile(*dat))
+	}
+
+	ta, err := newTurboActivate(*guid, opts...)
+
+	if err != nil {
+		return fail(stderr, err)
+	}
+
+	defer ta.Close()
+
+	c := &cli{
+		ta:      ta,
+		flags:   flags,
+		jsonOut: *jsonOut,
+		out:     stdout,
+	}
+
+	res, err := c.dispatch(fs.Arg(0), fs.Args()[1:])
+
+	if err != nil {
+		return fail(stderr, err)
+	}
+
+	c.print(res)
+
+	return c.exitCode
+}
+
+// fail prints the error and returns its exit code.
+func fail(stderr io.Writer, err error) int {
+	fmt.Fprintln(stderr, "turboactivate:", err)
+
+	var ue usageError
+	var tae *turboactivate.TAError
+
+	switch {
+	case errors.As(err, &ue):
+		return exitUsage
+	case errors.As(err, &tae):
+		// exit codes are truncated to 8 bits
+		if tae.Code >= exitHRESULTOther-exitHRESULTBase {
+			return exitHRESULTOther
+		}
+
+		return exitHRESULTBase + int(tae.Code)
+	default:
+		return exitError
+	}
+}
+
+func (c *cli) print(res result) {
+	if res == nil {
+		return
+	}
+
+	if c.jsonOut {
+		enc := json.NewEncoder(c.out)
+		enc.SetIndent("", "  ")
+		enc.Encode(res)
+		return
+	}
+
+	keys := make([]string, 0, len(res))
+
+	for k := range res {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		v := res[k]
+
+		if list, ok := v.([]string); ok {
+			v = strings.Join(list, ", ")
+		}
+
+		fmt.Fprintf(c.out, "%s: %v\n", k, v)
+	}
+}
+
+func (c *cli) dispatch(cmd string, args []string) (result, error) {
+	switch cmd {
+	case "status":
+		return c.status(args)
+	case "activate":
+		return c.activate(args)
+	case "deactivate":
+		return c.deactivate(args)
+	case "genuine":
+		return c.genuine(args)
+	case "trial":
+		return c.trial(args)
+	case "feature":
+		return c.feature(args)
+	case "extra-data":
+		return c.extraData(args)
+	case "offline":
+		return c.offline(args)
+	default:
+		return nil, usageError("unknown command \"" + cmd + "\"")
+	}
+}
+
+func (c *cli) status(args []string) (result, error) {
+	fs := flag.NewFlagSet("status", flag.ContinueOnError)
+	features := fs.String("features", "", "comma separated custom license fields to show")
+	offline := fs.Bool("offline", false, "don't contact the LimeLM servers (skip IsGenuineEx)")
+
+	if err := fs.Parse(args); err != nil || fs.NArg() != 0 {
+		return nil, usageError("usage: status [-features a,b] [-offline]")
+	}
+
+	var opts = turboactivate.StatusOptions{
+		SkipGenuineCheck: *offline,
+		TrialFlags:       c.flags,
+	}
+
+	for _, f := range strings.Split(*features, ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			opts.Features = append(opts.Features, f)
+		}
+	}
+
+	st, err := c.ta.Status(context.Background(), opts)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if st.Activated {
+		c.exitCode = genuineExitCode(st.GenuineResult)
+	} else {
+		c.exitCode = exitNotGenuine
+	}
+
+	// round trip through JSON to use the LicenseStatus field names
+	data, err := json.Marshal(st)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var res result
+
+	if err = json.Unmarshal(data, &res); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (c *cli) activate(args []string) (result, error) {
+	if len(args) < 1 || len(args) > 2 {
+		return nil, usageError("usage: activate <product key> [extra data]")
+	}
+
+	var extra string
+
+	if len(args) == 2 {
+		extra = args[1]
+	}
+
+	ok, err := c.ta.CheckAndSavePKey(args[0], c.flags)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !ok {
+		return nil, turboactivate.ErrInvalidPKey
+	}
+
+	if err = c.ta.Activate(extra); err != nil {
+		return nil, err
+	}
+
+	return result{"activated": true}, nil
+}
+
+func (c *cli) deactivate(args []string) (result, error) {
+	fs := flag.NewFlagSet("deactivate", flag.ContinueOnError)
+	erase := fs.Bool("erase", false, "erase the product key")
+
+	if err := fs.Parse(args); err != nil || fs.NArg() != 0 {
+		return nil, usageError("usage: deactivate [-erase]")
+	}
+
+	if err := c.ta.Deactivate(*erase); err != nil {
+		return nil, err
+	}
+
+	return result{"activated": false}, nil
+}
+
+func (c *cli) genuine(args []string) (result, error) {
+	fs := flag.NewFlagSet("genuine", flag.ContinueOnError)
+	days := fs.Uint("days", 90, "days between checks with the LimeLM servers")
+	grace := fs.Uint("grace", 14, "grace days on internet errors")
+	now := fs.Bool("now", false, "check with the LimeLM servers immediately (IsGenuine)")
+
+	if err := fs.Parse(args); err != nil || fs.NArg() != 0 {
+		return nil, usageError("usage: genuine [-days N] [-grace N] [-now]")
+	}
+
+	var res turboactivate.IsGenuineResult
+	var err error
+
+	if *now {
+		res, err = c.ta.IsGenuine()
+	} else {
+		res, err = c.ta.IsGenuineEx(uint32(*days), uint32(*grace), false, false)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	var name string
+
+	switch res {
+	case turboactivate.IGRGenuine:
+		name = "genuine"
+	case turboactivate.IGRGenuineFeaturesChanged:
+		name = "genuine_features_changed"
+	case turboactivate.IGRNotGenuine:
+		name = "not_genuine"
+	case turboactivate.IGRNotGenuineInVM:
+		name = "not_genuine_in_vm"
+	case turboactivate.IGRInternetError:
+		name = "internet_error"
+	}
+
+	c.exitCode = genuineExitCode(res)
+
+	return result{"result": name, "code": int(res)}, nil
+}
+
+// genuineExitCode returns the exit code for the result of a genuine check.
+func genuineExitCode(res turboactivate.IsGenuineResult) int {
+	switch res {
+	case turboactivate.IGRNotGenuine:
+		return exitNotGenuine
+	case turboactivate.IGRNotGenuineInVM:
+		return exitNotGenuineInVM
+	case turboactivate.IGRInternetError:
+		return exitInternetError
+	default:
+		return exitOK
+	}
+}
+
+func (c *cli) trial(args []string) (result, error) {
+	const usage = "usage: trial start [extra data] | trial days | trial extend <extension>"
+
+	if len(args) == 0 {
+		return nil, usageError(usage)
+	}
+
+	switch {
+	case args[0] == "start" && len(args) <= 2:
+		var extra string
+
+		if len(args) == 2 {
+			extra = args[1]
+		}
+
+		ok, err := c.ta.UseTrial(c.flags, extra)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if !ok {
+			return nil, turboactivate.ErrTrialExpired
+		}
+
+		days, err := c.ta.TrialDaysRemaining(c.flags)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return result{"trial_days_remaining": days}, nil
+
+	case args[0] == "days" && len(args) == 1:
+		days, err := c.ta.TrialDaysRemaining(c.flags)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return result{"trial_days_remaining": days}, nil
+
+	case args[0] == "extend" && len(args) == 2:
+		if err := c.ta.ExtendTrial(args[1], c.flags); err != nil {
+			return nil, err
+		}
+
+		days, err := c.ta.TrialDaysRemaining(c.flags)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return result{"trial_days_remaining": days}, nil
+
+	default:
+		return nil, usageError(usage)
+	}
+}
+
+func (c *cli) feature(args []string) (result, error) {
+	if len(args) != 2 || args[0] != "get" {
+		return nil, usageError("usage: feature get <name>")
+	}
+
+	value, err := c.ta.GetFeatureValue(args[1])
+
+	if err != nil {
+		return nil, err
+	}
+
+	return result{args[1]: value}, nil
+}
+
+func (c *cli) extraData(args []string) (result, error) {
+	if len(args) != 0 {
+		return nil, usageError("usage: extra-data")
+	}
+
+	extra, err := c.ta.GetExtraData()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return result{"extra_data": extra}, nil
+}
+
+func (c *cli) offline(args []string) (result, error) {
+	const usage = "usage: offline request <file> [extra data] | offline apply <file> | offline deactivate <file> [-erase]"
+
+	if len(args) < 2 {
+		return nil, usageError(usage)
+	}
+
+	switch args[0] {
+	case "request":
+		if len(args) > 3 {
+			return nil, usageError(usage)
+		}
+
+		var extra string
+
+		if len(args) == 3 {
+			extra = args[2]
+		}
+
+		if err := c.ta.ActivationRequestToFile(args[1], extra); err != nil {
+			return nil, err
+		}
+
+		return result{"request_file": args[1]}, nil
+
+	case "apply":
+		if len(args) != 2 {
+			return nil, usageError(usage)
+		}
+
+		if err := c.ta.ActivateFromFile(args[1]); err != nil {
+			return nil, err
+		}
+
+		return result{"activated": true}, nil
+
+	case "deactivate":
+		var erase bool
+
+		if len(args) == 3 {
+			if args[2] != "-erase" {
+				return nil, usageError(usage)
+			}
+
+			erase = true
+		} else if len(args) != 2 {
+			return nil, usageError(usage)
+		}
+
+		if err := c.ta.DeactivationRequestToFile(args[1], erase); err != nil {
+			return nil, err
+		}
+
+		return result{"request_file": args[1], "activated": false}, nil
+
+	default:
+		return nil, usageError(usage)
+	}
+}
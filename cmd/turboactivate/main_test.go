@@ -0,0 +1,137 @@
+// Copyright 2018 wyDay, LLC. All rights reserved.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"golang.wyday.com/turboactivate"
+	"golang.wyday.com/turboactivate/fake"
+)
+
+const (
+	testGUID = "18324776654b3946fc44a5f3.49025204"
+	testPKey = "AAAA-BBBB-CCCC-DDDD-EEEE-FFFF-GGGG"
+)
+
+// useFake makes run use b instead of the TurboActivate library.
+func useFake(t *testing.T, b *fake.Backend) {
+	t.Helper()
+
+	var orig = newTurboActivate
+
+	newTurboActivate = func(versionGUID string, opts ...turboactivate.Option) (*turboactivate.TurboActivate, error) {
+		return turboactivate.New(versionGUID, append(opts, turboactivate.WithBackend(b))...)
+	}
+
+	t.Cleanup(func() { newTurboActivate = orig })
+}
+
+// runCLI runs the command and returns its exit code and output.
+func runCLI(args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	var code = run(append([]string{"-guid", testGUID, "-user"}, args...), &stdout, &stderr)
+
+	return code, stdout.String(), stderr.String()
+}
+
+func TestStatusExitCode(t *testing.T) {
+	b := fake.New()
+	b.AddKey(testGUID, testPKey, fake.Key{Features: map[string]string{"seats": "5"}})
+	useFake(t, b)
+
+	code, out, _ := runCLI("status")
+
+	if code != exitNotGenuine {
+		t.Errorf("status before activating exited with %d; want %d", code, exitNotGenuine)
+	}
+
+	if !strings.Contains(out, "activated: false") {
+		t.Errorf("status output: %q", out)
+	}
+
+	if code, _, errOut := runCLI("activate", testPKey); code != exitOK {
+		t.Fatalf("activate exited with %d: %s", code, errOut)
+	}
+
+	code, out, _ = runCLI("-json", "status", "-features", "seats")
+
+	if code != exitOK {
+		t.Errorf("status after activating exited with %d; want 0", code)
+	}
+
+	var res map[string]interface{}
+
+	if err := json.Unmarshal([]byte(out), &res); err != nil {
+		t.Fatal(err)
+	}
+
+	if res["activated"] != true || res["features"].(map[string]interface{})["seats"] != "5" {
+		t.Errorf("status JSON: %s", out)
+	}
+
+	b.FailNext("IsGenuineEx", turboactivate.TAEInet)
+
+	if code, _, _ = runCLI("status"); code != exitInternetError {
+		t.Errorf("status with an internet error exited with %d; want %d", code, exitInternetError)
+	}
+
+	// -offline skips the genuine check
+	b.FailNext("IsGenuineEx", turboactivate.TAEInVM)
+
+	if code, _, _ = runCLI("status", "-offline"); code != exitOK {
+		t.Errorf("status -offline exited with %d; want 0", code)
+	}
+}
+
+func TestGenuineExitCode(t *testing.T) {
+	b := fake.New()
+	b.AddKey(testGUID, testPKey, fake.Key{})
+	useFake(t, b)
+
+	if code, _, _ := runCLI("genuine"); code != exitNotGenuine {
+		t.Errorf("genuine before activating exited with %d; want %d", code, exitNotGenuine)
+	}
+
+	runCLI("activate", testPKey)
+
+	if code, out, _ := runCLI("genuine"); code != exitOK || !strings.Contains(out, "result: genuine") {
+		t.Errorf("genuine exited with %d: %q", code, out)
+	}
+
+	b.FailNext("IsGenuineEx", turboactivate.TAEInVM)
+
+	if code, _, _ := runCLI("genuine"); code != exitNotGenuineInVM {
+		t.Errorf("genuine in a VM exited with %d; want %d", code, exitNotGenuineInVM)
+	}
+}
+
+func TestErrorExitCodes(t *testing.T) {
+	b := fake.New()
+	useFake(t, b)
+
+	if code, _, _ := runCLI("nope"); code != exitUsage {
+		t.Errorf("unknown command exited with %d; want %d", code, exitUsage)
+	}
+
+	t.Setenv("TURBOACTIVATE_GUID", "")
+
+	if code := run([]string{"status"}, &bytes.Buffer{}, &bytes.Buffer{}); code != exitUsage {
+		t.Errorf("missing -guid exited with %d; want %d", code, exitUsage)
+	}
+
+	// TA_E_PKEY
+	if code, _, _ := runCLI("feature", "get", "seats"); code != exitHRESULTBase+int(turboactivate.TAEPKey) {
+		t.Errorf("feature get without a key exited with %d; want %d", code, exitHRESULTBase+int(turboactivate.TAEPKey))
+	}
+
+	// the HRESULTs that don't fit in an exit code
+	b.FailNext("GetFeatureValue", 0x1AB)
+
+	if code, _, _ := runCLI("feature", "get", "seats"); code != exitHRESULTOther {
+		t.Errorf("feature get with the HRESULT 0x1AB exited with %d; want %d", code, exitHRESULTOther)
+	}
+}
@@ -0,0 +1,256 @@
+// Copyright 2018 wyDay, LLC. All rights reserved.
+
+package turboactivate // import "golang.wyday.com/turboactivate"
+
+import (
+	"context"
+	"time"
+)
+
+// runContext runs call (which calls into the TurboActivate library) on its
+// own goroutine and waits for it to finish or for ctx to be done, whichever
+// happens first.
+//
+// The library call itself can't be interrupted. So if ctx is done first, call
+// keeps running in the background until the library returns, and its result is
+// discarded. Whatever the library did (e.g. finishing an activation) is still
+// saved by the library and visible to the functions called afterwards. Since
+// the library calls are serialized per handle, those functions wait for the
+// abandoned call to finish, so they see the final state and not a half-finished
+// call. The Context functions also wait for it, but give up when ctx is done.
+//
+// Only one call per instance can be abandoned: inflight has room for one
+// call, so until the abandoned call returns, every other Context call on the
+// instance waits (and fails if its ctx is done before the call returns).
+func (ta *TurboActivate) runContext(ctx context.Context, call func()) error {
+
+	// the context can never be canceled, so there's nothing to wait for
+	if ctx.Done() == nil {
+		call()
+		return nil
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// wait for any previous (possibly abandoned) call to finish
+	select {
+	case ta.inflight <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	done := make(chan struct{})
+
+	go func() {
+		defer func() { <-ta.inflight }()
+
+		call()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		// prefer the result if the call finished at the same time
+		select {
+		case <-done:
+			return nil
+		default:
+			return ctx.Err()
+		}
+	}
+}
+
+// invoke calls the network-bound library function with runContext, and calls
+// it again as long as the RetryPolicy says the HRESULT should be retried.
+// Returns the last HRESULT and the number of attempts made.
+func (ta *TurboActivate) invoke(ctx context.Context, call func() HRESULT) (HRESULT, int, error) {
+
+	for attempt := 1; ; attempt++ {
+		var ret HRESULT
+
+		if err := ta.runContext(ctx, func() { ret = call() }); err != nil {
+			return 0, attempt, err
+		}
+
+		if !ta.retry.shouldRetry(ret, attempt) {
+			return ret, attempt, nil
+		}
+
+		timer := time.NewTimer(ta.retry.delay(attempt))
+
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return 0, attempt, ctx.Err()
+		}
+	}
+}
+
+// ActivateContext is like Activate, but returns ctx.Err() if ctx is canceled
+// or its deadline passes before the LimeLM servers respond.
+//
+// The TurboActivate library call can't be interrupted, so when ctx is done
+// first it's abandoned: it keeps running in the background, and what it did
+// (e.g. activating) is still saved. Only one call per instance can be
+// abandoned at a time. Until it returns, the other functions of the instance
+// wait for it, and the other Context functions fail with ctx.Err() if their
+// ctx is done before it returns. The same goes for every Context function.
+func (ta *TurboActivate) ActivateContext(ctx context.Context, extraData string) error {
+
+	ret, attempts, err := ta.invoke(ctx, func() HRESULT {
+		return ta.backend.Activate(ta.handle, extraData)
+	})
+
+	if err != nil {
+		return err
+	}
+
+	// TA_OK
+	if ret == 0x00 {
+		return nil
+	}
+
+	return retryErr(taHresultToErr(ret, "Activate"), attempts)
+}
+
+// IsGenuineContext is like IsGenuine, but returns ctx.Err() if ctx is canceled
+// or its deadline passes before the LimeLM servers respond.
+func (ta *TurboActivate) IsGenuineContext(ctx context.Context) (IsGenuineResult, error) {
+
+	ret, attempts, err := ta.invoke(ctx, func() HRESULT {
+		return ta.backend.IsGenuine(ta.handle)
+	})
+
+	if err != nil {
+		return IGRNotGenuine, err
+	}
+
+	switch ret {
+	case 0x00: // TA_OK
+		return IGRGenuine, nil
+
+	case 0x16: // TA_E_FEATURES_CHANGED
+		return IGRGenuineFeaturesChanged, nil
+
+	case 0x04: // TA_E_INET
+		return IGRInternetError, nil
+
+	case 0x01, 0x03, 0x06: // TA_FAIL, TA_E_ACTIVATE, TA_E_REVOKED
+		return IGRNotGenuine, nil
+
+	case 0x11: // TA_E_IN_VM
+		return IGRNotGenuineInVM, nil
+
+	default:
+		return IGRNotGenuine, retryErr(taHresultToErr(ret, "IsGenuine"), attempts)
+	}
+}
+
+// IsGenuineExContext is like IsGenuineEx, but returns ctx.Err() if ctx is canceled
+// or its deadline passes before the LimeLM servers respond.
+func (ta *TurboActivate) IsGenuineExContext(ctx context.Context, daysBetweenChecks uint32, graceDaysOnInetErr uint32, skipOffline bool, offlineShowInetErr bool) (IsGenuineResult, error) {
+
+	ret, attempts, err := ta.invoke(ctx, func() HRESULT {
+		return ta.backend.IsGenuineEx(ta.handle, daysBetweenChecks, graceDaysOnInetErr, skipOffline, offlineShowInetErr)
+	})
+
+	if err != nil {
+		return IGRNotGenuine, err
+	}
+
+	switch ret {
+	case 0x00: // TA_OK
+		return IGRGenuine, nil
+
+	case 0x16: // TA_E_FEATURES_CHANGED
+		return IGRGenuineFeaturesChanged, nil
+
+	case 0x04, 0x15: // TA_E_INET, TA_E_INET_DELAYED
+		return IGRInternetError, nil
+
+	case 0x01, 0x03, 0x06: // TA_FAIL, TA_E_ACTIVATE, TA_E_REVOKED
+		return IGRNotGenuine, nil
+
+	case 0x11: // TA_E_IN_VM
+		return IGRNotGenuineInVM, nil
+
+	default:
+		return IGRNotGenuine, retryErr(taHresultToErr(ret, "IsGenuineEx"), attempts)
+	}
+}
+
+// DeactivateContext is like Deactivate, but returns ctx.Err() if ctx is canceled
+// or its deadline passes before the LimeLM servers respond.
+func (ta *TurboActivate) DeactivateContext(ctx context.Context, eraseProductKey bool) error {
+
+	ret, attempts, err := ta.invoke(ctx, func() HRESULT {
+		return ta.backend.Deactivate(ta.handle, eraseProductKey)
+	})
+
+	if err != nil {
+		return err
+	}
+
+	// TA_OK
+	if ret == 0x00 {
+		return nil
+	}
+
+	return retryErr(taHresultToErr(ret, "Deactivate"), attempts)
+}
+
+// UseTrialContext is like UseTrial, but returns ctx.Err() if ctx is canceled
+// or its deadline passes before the LimeLM servers respond.
+func (ta *TurboActivate) UseTrialContext(ctx context.Context, flags TAFlags, extraData string) (bool, error) {
+
+	ret, attempts, err := ta.invoke(ctx, func() HRESULT {
+		return ta.backend.UseTrial(ta.handle, flags, extraData)
+	})
+
+	if err != nil {
+		return false, err
+	}
+
+	// TA_OK
+	if ret == 0x00 {
+		return true, nil
+	} else if ret == 0x1E { // TA_E_TRIAL_EXPIRED
+		return false, nil
+	}
+
+	return false, retryErr(taHresultToErr(ret, "UseTrial"), attempts)
+}
+
+// ExtendTrialContext is like ExtendTrial, but returns ctx.Err() if ctx is canceled
+// or its deadline passes before the LimeLM servers respond. TrialEventExtensionApplied
+// is raised when the trial is extended, even if the call was abandoned.
+func (ta *TurboActivate) ExtendTrialContext(ctx context.Context, trialExtension string, flags TAFlags) error {
+
+	ret, attempts, err := ta.invoke(ctx, func() HRESULT {
+		var ret = ta.backend.ExtendTrial(ta.handle, flags, trialExtension)
+
+		// raised here (and not after invoke returns) so it's raised even
+		// when the call is abandoned
+		if ret == 0x00 {
+			raiseTrialEvent(ta.state.key, TrialEventExtensionApplied)
+		}
+
+		return ret
+	})
+
+	if err != nil {
+		return err
+	}
+
+	// TA_OK
+	if ret == 0x00 {
+		return nil
+	}
+
+	return retryErr(taHresultToErr(ret, "ExtendTrial"), attempts)
+}
@@ -0,0 +1,130 @@
+// Copyright 2018 wyDay, LLC. All rights reserved.
+
+package turboactivate_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"golang.wyday.com/turboactivate"
+	"golang.wyday.com/turboactivate/fake"
+	"golang.wyday.com/turboactivate/internal/tatest"
+)
+
+// stallBackend blocks Activate and ExtendTrial until release is closed, like
+// a call waiting on the LimeLM servers.
+type stallBackend struct {
+	turboactivate.Backend
+
+	entered chan struct{}
+	release chan struct{}
+}
+
+func (s *stallBackend) stall() {
+	s.entered <- struct{}{}
+	<-s.release
+}
+
+func (s *stallBackend) Activate(handle uint32, extraData string) turboactivate.HRESULT {
+	s.stall()
+	return s.Backend.Activate(handle, extraData)
+}
+
+func (s *stallBackend) ExtendTrial(handle uint32, flags turboactivate.TAFlags, trialExtension string) turboactivate.HRESULT {
+	s.stall()
+	return s.Backend.ExtendTrial(handle, flags, trialExtension)
+}
+
+func newStallBackend() *stallBackend {
+	return &stallBackend{entered: make(chan struct{}, 1), release: make(chan struct{})}
+}
+
+// wrap is the WithBackendWrapper function that stalls the calls of inner.
+func (s *stallBackend) wrap(inner turboactivate.Backend) turboactivate.Backend {
+	s.Backend = inner
+	return s
+}
+
+func TestActivateContextAbandoned(t *testing.T) {
+	var s = newStallBackend()
+
+	ta := tatest.New(t, fake.New(), tatest.WithKey(nil), tatest.WithOptions(turboactivate.WithBackendWrapper(s.wrap)))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if err := ta.ActivateContext(ctx, ""); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("ActivateContext() = %v; want DeadlineExceeded", err)
+	}
+
+	<-s.entered
+
+	// the abandoned call is still running, so the next one waits for it
+	ctx2, cancel2 := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel2()
+
+	if err := ta.ActivateContext(ctx2, ""); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("ActivateContext() during the abandoned call = %v; want DeadlineExceeded", err)
+	}
+
+	close(s.release)
+
+	// IsActivated waits for the abandoned call, which still activated
+	if ok, err := ta.IsActivated(); !ok || err != nil {
+		t.Errorf("IsActivated() = %v, %v; want true", ok, err)
+	}
+}
+
+func TestContextCanceledBeforeCall(t *testing.T) {
+	b := fake.New()
+	ta := tatest.New(t, b)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := ta.IsGenuineExContext(ctx, 90, 14, false, false); !errors.Is(err, context.Canceled) {
+		t.Errorf("IsGenuineExContext() = %v; want Canceled", err)
+	}
+
+	if n := b.Calls("IsGenuineEx"); n != 0 {
+		t.Errorf("IsGenuineEx was called %d times", n)
+	}
+}
+
+func TestExtendTrialContextAbandonedRaisesEvent(t *testing.T) {
+	b := fake.New()
+	b.AddProduct(testGUID, fake.Product{TrialDays: 10, TrialExtensions: map[string]uint32{"EXT": 5}})
+
+	var s = newStallBackend()
+
+	ta := tatest.New(t, b, tatest.WithOptions(turboactivate.WithBackendWrapper(s.wrap)))
+
+	var flags = turboactivate.TAUser | turboactivate.TAVerifiedTrial
+
+	if _, err := ta.UseTrial(flags, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	var events = make(chan turboactivate.TrialEvent, 1)
+	ta.SetTrialCallback(func(ev turboactivate.TrialEvent) { events <- ev })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if err := ta.ExtendTrialContext(ctx, "EXT", flags); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("ExtendTrialContext() = %v; want DeadlineExceeded", err)
+	}
+
+	<-s.entered
+	close(s.release)
+
+	if ev := waitEvent(t, events); ev.Type != turboactivate.TrialEventExtensionApplied {
+		t.Errorf("event = %v; want ExtensionApplied", ev.Type)
+	}
+
+	if days, err := ta.TrialDaysRemaining(flags); days != 15 || err != nil {
+		t.Errorf("TrialDaysRemaining() = %d, %v; want 15", days, err)
+	}
+}
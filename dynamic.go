@@ -0,0 +1,190 @@
+// Copyright 2018 wyDay, LLC. All rights reserved.
+
+package turboactivate // import "golang.wyday.com/turboactivate"
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// LibraryEnv is the environment variable that overrides where LoadLibrary
+// looks for the TurboActivate library. It can be the path of the library or
+// of the directory that has it.
+const LibraryEnv = "TURBOACTIVATE_LIBRARY"
+
+// LibraryNotFoundError is returned by LoadLibrary when none of the paths
+// has a TurboActivate library it can load.
+type LibraryNotFoundError struct {
+	// Tried are the paths that were tried, in order.
+	Tried []string
+
+	// Err is why the last path failed to load.
+	Err error
+}
+
+func (e *LibraryNotFoundError) Error() string {
+	var msg = "The TurboActivate library couldn't be loaded"
+
+	if len(e.Tried) != 0 {
+		msg += " (tried " + strings.Join(e.Tried, ", ") + ")"
+	}
+
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+
+	return msg
+}
+
+func (e *LibraryNotFoundError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is a *LibraryNotFoundError, so
+// errors.Is(err, ErrLibraryNotFound) matches the errors of LoadLibrary.
+func (e *LibraryNotFoundError) Is(target error) bool {
+	_, ok := target.(*LibraryNotFoundError)
+	return ok
+}
+
+// ErrLibraryNotFound matches (with errors.Is) the errors returned by
+// LoadLibrary when the TurboActivate library can't be loaded.
+var ErrLibraryNotFound error = &LibraryNotFoundError{}
+
+// Library is a TurboActivate library loaded at runtime by LoadLibrary.
+// It stays loaded until the process exits.
+type Library struct {
+	path    string
+	backend Backend
+}
+
+// loadedLibraries has the libraries loaded by LoadLibrary, by path, so
+// loading the same library twice returns the same Library.
+var (
+	loadedLibrariesMu sync.Mutex
+	loadedLibraries   = map[string]*Library{}
+)
+
+// loadedLibrary is the library loaded when the package is built with the
+// "turboactivate_dynamic" build tag.
+var loadedLibrary *Library
+
+// LibraryName is the file name of the TurboActivate library on this platform.
+func LibraryName() string {
+	switch runtime.GOOS {
+	case "windows":
+		return "TurboActivate.dll"
+	case "darwin":
+		return "libTurboActivate.dylib"
+	default:
+		return "libTurboActivate.so"
+	}
+}
+
+// LibrarySearchPaths returns the paths LoadLibrary tries when it isn't
+// passed any. If the TURBOACTIVATE_LIBRARY environment variable is set, it's
+// the only path tried. Otherwise the paths are, in order:
+//
+//  1. the directory of the executable
+//  2. the library name alone, so the system's search order is used
+//     (e.g. LD_LIBRARY_PATH on Linux, or the DLL search order on Windows)
+//
+// The working directory isn't searched, so a library planted there isn't loaded.
+func LibrarySearchPaths() []string {
+
+	if env := os.Getenv(LibraryEnv); env != "" {
+		if fi, err := os.Stat(env); err == nil && fi.IsDir() {
+			return []string{filepath.Join(env, LibraryName())}
+		}
+
+		return []string{env}
+	}
+
+	var paths []string
+
+	if exe, err := os.Executable(); err == nil {
+		paths = append(paths, filepath.Join(filepath.Dir(exe), LibraryName()))
+	}
+
+	return append(paths, LibraryName())
+}
+
+// LoadLibrary loads the TurboActivate library from the first of the paths
+// that has one, or from LibrarySearchPaths() if no paths are passed. The
+// returned error matches ErrLibraryNotFound if none of them could be loaded.
+//
+// Use the library with WithLibrary (or pass Backend() to WithBackend):
+//
+//	lib, err := turboactivate.LoadLibrary()
+//	ta, err := turboactivate.New(guid, turboactivate.WithBackend(lib.Backend()))
+func LoadLibrary(paths ...string) (*Library, error) {
+
+	if len(paths) == 0 {
+		paths = LibrarySearchPaths()
+	}
+
+	loadedLibrariesMu.Lock()
+	defer loadedLibrariesMu.Unlock()
+
+	var lastErr error
+
+	for _, path := range paths {
+		if lib, ok := loadedLibraries[path]; ok {
+			return lib, nil
+		}
+
+		backend, err := openLibrary(path)
+
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		var lib = &Library{path: path, backend: backend}
+		loadedLibraries[path] = lib
+		return lib, nil
+	}
+
+	return nil, &LibraryNotFoundError{Tried: paths, Err: lastErr}
+}
+
+// LoadedLibrary returns the library the package loaded when it was built
+// with the "turboactivate_dynamic" build tag, or nil if it wasn't (or the
+// library wasn't found).
+func LoadedLibrary() *Library {
+	return loadedLibrary
+}
+
+// Path returns the path the library was loaded from, as it was passed to
+// LoadLibrary (or returned by LibrarySearchPaths).
+func (l *Library) Path() string {
+	return l.path
+}
+
+// Backend returns the Backend that calls into the library.
+func (l *Library) Backend() Backend {
+	return l.backend
+}
+
+// Version gets the version of the library (e.g. 4, 4, 4, 0 for "4.4.4.0").
+func (l *Library) Version() (major uint32, minor uint32, build uint32, revision uint32, err error) {
+	return versionOf(serialize(l.backend))
+}
+
+// WithLibrary makes the TurboActivate instance use the TurboActivate library
+// loaded at runtime from the first of the paths that has one (see LoadLibrary).
+func WithLibrary(paths ...string) Option {
+	return func(c *config) error {
+		lib, err := LoadLibrary(paths...)
+
+		if err != nil {
+			return err
+		}
+
+		c.backend = lib.Backend()
+		return nil
+	}
+}
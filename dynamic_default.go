@@ -0,0 +1,21 @@
+// Copyright 2018 wyDay, LLC. All rights reserved.
+
+// +build turboactivate_dynamic
+
+package turboactivate // import "golang.wyday.com/turboactivate"
+
+// With the "turboactivate_dynamic" build tag the library isn't linked, but
+// loaded when the program starts from LibrarySearchPaths(). If it isn't
+// found, the functions that use the default backend return the
+// LibraryNotFoundError instead of ErrNoBackend.
+func init() {
+	lib, err := LoadLibrary()
+
+	if err != nil {
+		defaultBackendErr = err
+		return
+	}
+
+	loadedLibrary = lib
+	defaultBackend = lib.Backend()
+}
@@ -0,0 +1,12 @@
+// Copyright 2018 wyDay, LLC. All rights reserved.
+
+// +build !cgo,!windows
+
+package turboactivate // import "golang.wyday.com/turboactivate"
+
+import "errors"
+
+// openLibrary can't load the library without cgo (except on Windows).
+func openLibrary(path string) (Backend, error) {
+	return nil, errors.New("Loading the TurboActivate library at runtime needs cgo on this platform")
+}
@@ -0,0 +1,53 @@
+// Copyright 2018 wyDay, LLC. All rights reserved.
+
+package turboactivate_test
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"golang.wyday.com/turboactivate"
+)
+
+func TestLoadLibraryNotFound(t *testing.T) {
+	var missing = filepath.Join(t.TempDir(), turboactivate.LibraryName())
+
+	_, err := turboactivate.LoadLibrary(missing)
+
+	if !errors.Is(err, turboactivate.ErrLibraryNotFound) {
+		t.Fatalf("LoadLibrary() = %v; want ErrLibraryNotFound", err)
+	}
+
+	var notFound *turboactivate.LibraryNotFoundError
+
+	if !errors.As(err, &notFound) || len(notFound.Tried) != 1 || notFound.Tried[0] != missing {
+		t.Errorf("LoadLibrary() tried %v; want %s", notFound.Tried, missing)
+	}
+
+	if _, err = turboactivate.New(testGUID, turboactivate.WithLibrary(missing)); !errors.Is(err, turboactivate.ErrLibraryNotFound) {
+		t.Errorf("New(WithLibrary()) = %v; want ErrLibraryNotFound", err)
+	}
+}
+
+func TestLibrarySearchPaths(t *testing.T) {
+	var dir = t.TempDir()
+
+	t.Setenv(turboactivate.LibraryEnv, dir)
+
+	if paths := turboactivate.LibrarySearchPaths(); len(paths) != 1 || paths[0] != filepath.Join(dir, turboactivate.LibraryName()) {
+		t.Errorf("LibrarySearchPaths() with a directory = %v", paths)
+	}
+
+	t.Setenv(turboactivate.LibraryEnv, "/opt/ta/custom.so")
+
+	if paths := turboactivate.LibrarySearchPaths(); len(paths) != 1 || paths[0] != "/opt/ta/custom.so" {
+		t.Errorf("LibrarySearchPaths() with a file = %v", paths)
+	}
+
+	t.Setenv(turboactivate.LibraryEnv, "")
+
+	if paths := turboactivate.LibrarySearchPaths(); paths[len(paths)-1] != turboactivate.LibraryName() {
+		t.Errorf("LibrarySearchPaths() = %v; want the library name last", paths)
+	}
+}
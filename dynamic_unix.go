@@ -0,0 +1,70 @@
+// Copyright 2018 wyDay, LLC. All rights reserved.
+
+// +build cgo,!windows
+
+package turboactivate // import "golang.wyday.com/turboactivate"
+
+/*
+#cgo linux LDFLAGS: -ldl
+
+#include <dlfcn.h>
+#include <stdlib.h>
+#include <string.h>
+
+// ta_dyn_open loads the library at path. On failure it returns NULL and sets
+// err to a copy of the dlerror() message (which must be freed).
+static void * ta_dyn_open(const char * path, char ** err)
+{
+	void * lib = dlopen(path, RTLD_NOW | RTLD_LOCAL);
+
+	*err = NULL;
+
+	if (lib == NULL)
+	{
+		// dlerror() is per thread, so it's copied before returning to Go
+		const char * msg = dlerror();
+
+		if (msg != NULL)
+			*err = strdup(msg);
+	}
+
+	return lib;
+}
+*/
+import "C"
+import (
+	"errors"
+	"unsafe"
+)
+
+// openLibrary loads the TurboActivate library at path with dlopen.
+func openLibrary(path string) (Backend, error) {
+
+	var nativePath = C.CString(path)
+	var nativeErr *C.char
+
+	var lib = C.ta_dyn_open(nativePath, &nativeErr)
+
+	C.free(unsafe.Pointer(nativePath))
+
+	if lib == nil {
+		if nativeErr != nil {
+			var msg = C.GoString(nativeErr)
+			C.free(unsafe.Pointer(nativeErr))
+			return nil, errors.New(msg)
+		}
+
+		return nil, errors.New(path + " couldn't be loaded")
+	}
+
+	procs, missing := newCgoProcs(func(name *C.char) unsafe.Pointer {
+		return C.dlsym(lib, name)
+	})
+
+	if missing != "" {
+		C.dlclose(lib)
+		return nil, errors.New(path + " doesn't have the " + missing + " function")
+	}
+
+	return procBackend{procs}, nil
+}
@@ -0,0 +1,79 @@
+// Copyright 2018 wyDay, LLC. All rights reserved.
+
+// +build windows
+
+package turboactivate // import "golang.wyday.com/turboactivate"
+
+import (
+	"errors"
+	"runtime"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+// dllProcs calls the TA_* functions of a TurboActivate.dll loaded with
+// LoadLibrary, without cgo.
+//
+// The TA_* functions are __cdecl. syscall calls them correctly on 386 too,
+// since it restores the stack pointer after the call.
+type dllProcs struct {
+	procs *[numProcs]*syscall.Proc
+}
+
+// dllTrialCallback is the TrialChangeCallback passed to every library. Only
+// a limited number of callbacks can be created, so it's created once.
+var (
+	dllTrialCallbackOnce sync.Once
+	dllTrialCallback     uintptr
+)
+
+// openLibrary loads the TurboActivate library at path with LoadLibrary.
+func openLibrary(path string) (Backend, error) {
+
+	dll, err := syscall.LoadDLL(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var procs = new([numProcs]*syscall.Proc)
+
+	for proc, name := range procNames {
+		if procs[proc], err = dll.FindProc(name); err != nil {
+			dll.Release()
+			return nil, errors.New(path + " doesn't have the " + name + " function")
+		}
+	}
+
+	return procBackend{dllProcs{procs}}, nil
+}
+
+func (p dllProcs) call(proc taProc, args ...uintptr) uintptr {
+	r, _, _ := p.procs[proc].Call(args...)
+	return r
+}
+
+func (dllProcs) trialCallback() uintptr {
+
+	dllTrialCallbackOnce.Do(func() {
+		dllTrialCallback = syscall.NewCallbackCDecl(func(status uintptr, userDefinedPtr uintptr) uintptr {
+			nativeTrialCallback(uint32(status), userDefinedPtr)
+			return 0
+		})
+	})
+
+	return dllTrialCallback
+}
+
+// alloc returns Go memory, since there's no C allocator without cgo. Unlike
+// cgo calls, syscall calls may be passed Go memory: taMem keeps it alive
+// until it's freed, and the garbage collector doesn't move it.
+func (dllProcs) alloc(size uintptr) unsafe.Pointer {
+	var words = make([]uintptr, (size+unsafe.Sizeof(uintptr(0))-1)/unsafe.Sizeof(uintptr(0)))
+	return unsafe.Pointer(&words[0])
+}
+
+func (dllProcs) free(p unsafe.Pointer) {
+	runtime.KeepAlive(p)
+}
@@ -0,0 +1,284 @@
+// Copyright 2018 wyDay, LLC. All rights reserved.
+
+package turboactivate // import "golang.wyday.com/turboactivate"
+
+import (
+	"strconv"
+	"strings"
+)
+
+// HRESULT is the raw result code returned by the TurboActivate library functions.
+type HRESULT uint32
+
+// The HRESULT values returned by the TurboActivate library (see TurboActivate.h).
+const (
+	TAOK                     HRESULT = 0x00 // TA_OK
+	TAFail                   HRESULT = 0x01 // TA_FAIL
+	TAEPKey                  HRESULT = 0x02 // TA_E_PKEY
+	TAEActivate              HRESULT = 0x03 // TA_E_ACTIVATE
+	TAEInet                  HRESULT = 0x04 // TA_E_INET
+	TAEInUse                 HRESULT = 0x05 // TA_E_INUSE
+	TAERevoked               HRESULT = 0x06 // TA_E_REVOKED
+	TAEPDets                 HRESULT = 0x08 // TA_E_PDETS
+	TAETrial                 HRESULT = 0x09 // TA_E_TRIAL
+	TAECom                   HRESULT = 0x0B // TA_E_COM
+	TAETrialEUsed            HRESULT = 0x0C // TA_E_TRIAL_EUSED
+	TAEExpired               HRESULT = 0x0D // TA_E_EXPIRED
+	TAEPermission            HRESULT = 0x0F // TA_E_PERMISSION
+	TAEInvalidFlags          HRESULT = 0x10 // TA_E_INVALID_FLAGS
+	TAEInVM                  HRESULT = 0x11 // TA_E_IN_VM
+	TAEEDataLong             HRESULT = 0x12 // TA_E_EDATA_LONG
+	TAEInvalidArgs           HRESULT = 0x13 // TA_E_INVALID_ARGS
+	TAEKeyForTurboFloat      HRESULT = 0x14 // TA_E_KEY_FOR_TURBOFLOAT
+	TAEInetDelayed           HRESULT = 0x15 // TA_E_INET_DELAYED
+	TAEFeaturesChanged       HRESULT = 0x16 // TA_E_FEATURES_CHANGED
+	TAENoMoreDeactivations   HRESULT = 0x18 // TA_E_NO_MORE_DEACTIVATIONS
+	TAEAccountCanceled       HRESULT = 0x19 // TA_E_ACCOUNT_CANCELED
+	TAEAlreadyActivated      HRESULT = 0x1A // TA_E_ALREADY_ACTIVATED
+	TAEInvalidHandle         HRESULT = 0x1B // TA_E_INVALID_HANDLE
+	TAEEnableNetworkAdapters HRESULT = 0x1C // TA_E_ENABLE_NETWORK_ADAPTERS
+	TAEAlreadyVerifiedTrial  HRESULT = 0x1D // TA_E_ALREADY_VERIFIED_TRIAL
+	TAETrialExpired          HRESULT = 0x1E // TA_E_TRIAL_EXPIRED
+	TAEMustSpecifyTrialType  HRESULT = 0x1F // TA_E_MUST_SPECIFY_TRIAL_TYPE
+	TAEMustUseTrial          HRESULT = 0x20 // TA_E_MUST_USE_TRIAL
+	TAENoMoreTrialsAllowed   HRESULT = 0x21 // TA_E_NO_MORE_TRIALS_ALLOWED
+	TAEBrokenWMI             HRESULT = 0x22 // TA_E_BROKEN_WMI
+	TAEInetTimeout           HRESULT = 0x23 // TA_E_INET_TIMEOUT
+	TAEInetTLS               HRESULT = 0x24 // TA_E_INET_TLS
+)
+
+// hresultNames are the names of the HRESULTs in TurboActivate.h.
+var hresultNames = map[HRESULT]string{
+	TAOK:                     "TA_OK",
+	TAFail:                   "TA_FAIL",
+	TAEPKey:                  "TA_E_PKEY",
+	TAEActivate:              "TA_E_ACTIVATE",
+	TAEInet:                  "TA_E_INET",
+	TAEInUse:                 "TA_E_INUSE",
+	TAERevoked:               "TA_E_REVOKED",
+	TAEPDets:                 "TA_E_PDETS",
+	TAETrial:                 "TA_E_TRIAL",
+	TAECom:                   "TA_E_COM",
+	TAETrialEUsed:            "TA_E_TRIAL_EUSED",
+	TAEExpired:               "TA_E_EXPIRED",
+	TAEPermission:            "TA_E_PERMISSION",
+	TAEInvalidFlags:          "TA_E_INVALID_FLAGS",
+	TAEInVM:                  "TA_E_IN_VM",
+	TAEEDataLong:             "TA_E_EDATA_LONG",
+	TAEInvalidArgs:           "TA_E_INVALID_ARGS",
+	TAEKeyForTurboFloat:      "TA_E_KEY_FOR_TURBOFLOAT",
+	TAEInetDelayed:           "TA_E_INET_DELAYED",
+	TAEFeaturesChanged:       "TA_E_FEATURES_CHANGED",
+	TAENoMoreDeactivations:   "TA_E_NO_MORE_DEACTIVATIONS",
+	TAEAccountCanceled:       "TA_E_ACCOUNT_CANCELED",
+	TAEAlreadyActivated:      "TA_E_ALREADY_ACTIVATED",
+	TAEInvalidHandle:         "TA_E_INVALID_HANDLE",
+	TAEEnableNetworkAdapters: "TA_E_ENABLE_NETWORK_ADAPTERS",
+	TAEAlreadyVerifiedTrial:  "TA_E_ALREADY_VERIFIED_TRIAL",
+	TAETrialExpired:          "TA_E_TRIAL_EXPIRED",
+	TAEMustSpecifyTrialType:  "TA_E_MUST_SPECIFY_TRIAL_TYPE",
+	TAEMustUseTrial:          "TA_E_MUST_USE_TRIAL",
+	TAENoMoreTrialsAllowed:   "TA_E_NO_MORE_TRIALS_ALLOWED",
+	TAEBrokenWMI:             "TA_E_BROKEN_WMI",
+	TAEInetTimeout:           "TA_E_INET_TIMEOUT",
+	TAEInetTLS:               "TA_E_INET_TLS",
+}
+
+// String returns the name of the HRESULT in TurboActivate.h (e.g. "TA_E_INET"),
+// or its hex value if it's unknown (e.g. "0x7F").
+func (hr HRESULT) String() string {
+	if name, ok := hresultNames[hr]; ok {
+		return name
+	}
+
+	return "0x" + strings.ToUpper(strconv.FormatUint(uint64(hr), 16))
+}
+
+// TAError is the error returned by the TurboActivate functions when the
+// TurboActivate library returns a failure HRESULT.
+//
+// Use errors.Is with one of the Err* values to check for a specific failure
+// (e.g. errors.Is(err, ErrRevoked)), or errors.As with a *TAError to get the
+// raw HRESULT and the name of the function that failed.
+type TAError struct {
+	// Code is the raw HRESULT returned by the TurboActivate library.
+	Code HRESULT
+
+	// Func is the name of the TurboActivate function that failed.
+	// It's empty for the Err* sentinel values.
+	Func string
+
+	// Message is the human readable description of the error.
+	Message string
+}
+
+// Error returns the description of the error.
+func (e *TAError) Error() string {
+	return e.Message
+}
+
+// Is reports whether target is a *TAError with the same HRESULT code.
+// This lets errors.Is match any error returned from any function
+// against the Err* sentinel values.
+func (e *TAError) Is(target error) bool {
+	t, ok := target.(*TAError)
+
+	if !ok {
+		return false
+	}
+
+	return e.Code == t.Code && (t.Func == "" || t.Func == e.Func)
+}
+
+var (
+	// ErrFail is a general failure (TA_FAIL).
+	ErrFail = &TAError{Code: TAFail, Message: "general failure"}
+
+	// ErrInvalidPKey means the product key is invalid or there's no product key (TA_E_PKEY).
+	ErrInvalidPKey = &TAError{Code: TAEPKey, Message: "The product key is invalid or there's no product key"}
+
+	// ErrNotActivated means the product needs to be activated (TA_E_ACTIVATE).
+	ErrNotActivated = &TAError{Code: TAEActivate, Message: "The product needs to be activated"}
+
+	// ErrInet means the connection to the servers failed (TA_E_INET).
+	// More information here: https://wyday.com/limelm/help/faq/#internet-error
+	ErrInet = &TAError{Code: TAEInet, Message: "Connection to the servers failed"}
+
+	// ErrInUse means the product key has already been activated with the
+	// maximum number of computers (TA_E_INUSE).
+	ErrInUse = &TAError{Code: TAEInUse, Message: "The product key has already been activated with the maximum number of computers"}
+
+	// ErrRevoked means the product key has been revoked (TA_E_REVOKED).
+	ErrRevoked = &TAError{Code: TAERevoked, Message: "The product key has been revoked"}
+
+	// ErrPDets means the product details file (TurboActivate.dat) failed to load (TA_E_PDETS).
+	ErrPDets = &TAError{Code: TAEPDets, Message: "The product details file \"TurboActivate.dat\" failed to load. It's either missing or corrupt"}
+
+	// ErrTrialCorrupted means the trial data has been corrupted (TA_E_TRIAL).
+	ErrTrialCorrupted = &TAError{Code: TAETrial, Message: "The trial data has been corrupted, using the oldest date possible"}
+
+	// ErrCOM means CoInitializeEx failed (TA_E_COM).
+	ErrCOM = &TAError{Code: TAECom, Message: "CoInitializeEx failed. Re-enable Windows Management Instrumentation (WMI) service. Contact your system admin for more information"}
+
+	// ErrTrialExtUsed means the trial extension has already been used (TA_E_TRIAL_EUSED).
+	ErrTrialExtUsed = &TAError{Code: TAETrialEUsed, Message: "The trial extension has already been used"}
+
+	// ErrExpired means the activation has expired or the system time has
+	// been tampered with (TA_E_EXPIRED).
+	ErrExpired = &TAError{Code: TAEExpired, Message: "The activation has expired or the system time has been tampered with. Ensure your time, timezone, and date settings are correct. After fixing them restart your computer"}
+
+	// ErrPermission means insufficient system permission (TA_E_PERMISSION).
+	ErrPermission = &TAError{Code: TAEPermission, Message: "Insufficient system permission. Either start your process as an admin / elevated user or call the function again with the TA_USER flag"}
+
+	// ErrInvalidFlags means the flags passed to the function were invalid or missing (TA_E_INVALID_FLAGS).
+	ErrInvalidFlags = &TAError{Code: TAEInvalidFlags, Message: "The flags you passed to the function were invalid (or missing). Flags like \"TA_SYSTEM\" and \"TA_USER\" are mutually exclusive -- you can only use one or the other"}
+
+	// ErrInVM means the function failed because it's running inside a
+	// virtual machine / hypervisor (TA_E_IN_VM).
+	ErrInVM = &TAError{Code: TAEInVM, Message: "The function failed because this instance of your program is running inside a virtual machine / hypervisor and you've prevented the function from running inside a VM"}
+
+	// ErrExtraDataTooLong means the "extra data" was too long (TA_E_EDATA_LONG).
+	ErrExtraDataTooLong = &TAError{Code: TAEEDataLong, Message: "The \"extra data\" was too long. You're limited to 255 UTF-8 characters. Or, on Windows, a Unicode string that will convert into 255 UTF-8 characters or less"}
+
+	// ErrInvalidArgs means the arguments passed to the function are invalid (TA_E_INVALID_ARGS).
+	ErrInvalidArgs = &TAError{Code: TAEInvalidArgs, Message: "The arguments passed to the function are invalid. Double check your logic"}
+
+	// ErrKeyForTurboFloat means the product key is for TurboFloat Server,
+	// not TurboActivate (TA_E_KEY_FOR_TURBOFLOAT).
+	ErrKeyForTurboFloat = &TAError{Code: TAEKeyForTurboFloat, Message: "The product key used is for TurboFloat Server, not TurboActivate"}
+
+	// ErrNoMoreDeactivations means no more deactivations are allowed for
+	// the product key (TA_E_NO_MORE_DEACTIVATIONS).
+	ErrNoMoreDeactivations = &TAError{Code: TAENoMoreDeactivations, Message: "No more deactivations are allowed for the product key. This product is still activated on this computer"}
+
+	// ErrAccountCanceled means the LimeLM account is cancelled (TA_E_ACCOUNT_CANCELED).
+	ErrAccountCanceled = &TAError{Code: TAEAccountCanceled, Message: "Can't activate because the LimeLM account is cancelled"}
+
+	// ErrAlreadyActivated means the app is already activated with a
+	// product key (TA_E_ALREADY_ACTIVATED).
+	ErrAlreadyActivated = &TAError{Code: TAEAlreadyActivated, Message: "You can't use a product key because your app is already activated with a product key. To use a new product key, then first deactivate using either the Deactivate() or DeactivationRequestToFile()"}
+
+	// ErrInvalidHandle means the handle is not valid (TA_E_INVALID_HANDLE).
+	ErrInvalidHandle = &TAError{Code: TAEInvalidHandle, Message: "The handle is not valid. You must set a valid VersionGUID when constructing TurboActivate object"}
+
+	// ErrEnableNetworkAdapters means there are disabled network adapters
+	// whose hardware properties couldn't be read (TA_E_ENABLE_NETWORK_ADAPTERS).
+	// More information here: https://wyday.com/limelm/help/faq/#disabled-adapters
+	ErrEnableNetworkAdapters = &TAError{Code: TAEEnableNetworkAdapters, Message: "There are network adapters on the system that are disabled and TurboActivate couldn't read their hardware properties (even after trying and failing to enable the adapters automatically). Enable the network adapters, re-run the function, and TurboActivate will be able to \"remember\" the adapters even if the adapters are disabled in the future"}
+
+	// ErrAlreadyVerifiedTrial means the trial is already a verified trial (TA_E_ALREADY_VERIFIED_TRIAL).
+	ErrAlreadyVerifiedTrial = &TAError{Code: TAEAlreadyVerifiedTrial, Message: "The trial is already a verified trial. You need to use the \"TA_VERIFIED_TRIAL\" flag. Can't \"downgrade\" a verified trial to an unverified trial"}
+
+	// ErrTrialExpired means the verified trial has expired (TA_E_TRIAL_EXPIRED).
+	ErrTrialExpired = &TAError{Code: TAETrialExpired, Message: "The verified trial has expired. You must request a trial extension from the company"}
+
+	// ErrMustSpecifyTrialType means the trial type flags are missing or
+	// conflicting (TA_E_MUST_SPECIFY_TRIAL_TYPE).
+	ErrMustSpecifyTrialType = &TAError{Code: TAEMustSpecifyTrialType, Message: "You must specify the trial type (TA_UNVERIFIED_TRIAL or TA_VERIFIED_TRIAL). And you can't use both flags. Choose one or the other. We recommend TA_VERIFIED_TRIAL"}
+
+	// ErrMustUseTrial means UseTrial() must be called first (TA_E_MUST_USE_TRIAL).
+	ErrMustUseTrial = &TAError{Code: TAEMustUseTrial, Message: "You must call TA_UseTrial() before you can get the number of trial days remaining"}
+
+	// ErrNoMoreTrialsAllowed means no more verified trials can be made (TA_E_NO_MORE_TRIALS_ALLOWED).
+	ErrNoMoreTrialsAllowed = &TAError{Code: TAENoMoreTrialsAllowed, Message: "In the LimeLM account either the trial days is set to 0, OR the account is set to not auto-upgrade and thus no more verified trials can be made"}
+
+	// ErrBrokenWMI means the WMI repository on the computer is broken (TA_E_BROKEN_WMI).
+	ErrBrokenWMI = &TAError{Code: TAEBrokenWMI, Message: "The WMI repository on the computer is broken. To fix the WMI repository see the instructions here: https://wyday.com/limelm/help/faq/#fix-broken-wmi"}
+
+	// ErrInetTimeout means the connection to the server timed out (TA_E_INET_TIMEOUT).
+	ErrInetTimeout = &TAError{Code: TAEInetTimeout, Message: "The connection to the server timed out because a long period of time elapsed since the last data was sent or received"}
+
+	// ErrInetTLS means the secure connection to the activation servers
+	// failed due to a TLS or certificate error (TA_E_INET_TLS).
+	// More information here: https://wyday.com/limelm/help/faq/#internet-error
+	ErrInetTLS = &TAError{Code: TAEInetTLS, Message: "The secure connection to the activation servers failed due to a TLS or certificate error. More information here: https://wyday.com/limelm/help/faq/#internet-error"}
+)
+
+// knownErrors maps the HRESULT codes to their sentinel errors.
+var knownErrors = map[HRESULT]*TAError{}
+
+func init() {
+	for _, e := range []*TAError{
+		ErrFail, ErrInvalidPKey, ErrNotActivated, ErrInet, ErrInUse, ErrRevoked,
+		ErrPDets, ErrTrialCorrupted, ErrCOM, ErrTrialExtUsed, ErrExpired,
+		ErrPermission, ErrInvalidFlags, ErrInVM, ErrExtraDataTooLong,
+		ErrInvalidArgs, ErrKeyForTurboFloat, ErrNoMoreDeactivations,
+		ErrAccountCanceled, ErrAlreadyActivated, ErrInvalidHandle,
+		ErrEnableNetworkAdapters, ErrAlreadyVerifiedTrial, ErrTrialExpired,
+		ErrMustSpecifyTrialType, ErrMustUseTrial, ErrNoMoreTrialsAllowed,
+		ErrBrokenWMI, ErrInetTimeout, ErrInetTLS,
+	} {
+		knownErrors[e.Code] = e
+	}
+}
+
+// newTAError creates the *TAError for the HRESULT returned by funcName.
+func newTAError(ret HRESULT, funcName string) *TAError {
+	known, ok := knownErrors[ret]
+
+	if !ok {
+		// Make sure you're using the latest turboactivate.go, we occassionally add new error codes
+		// and you need latest version of this file to get a detailed description of the error.
+
+		// More information about upgrading here: https://wyday.com/limelm/help/faq/#update-libs
+
+		// You can also view error directly from the source: TurboActivate.h
+		return &TAError{
+			Code:    ret,
+			Func:    funcName,
+			Message: funcName + " failed with an unknown error code: " + strconv.FormatUint(uint64(ret), 10),
+		}
+	}
+
+	var msg = known.Message
+
+	if ret == TAFail {
+		msg = funcName + " " + msg
+	}
+
+	return &TAError{
+		Code:    ret,
+		Func:    funcName,
+		Message: msg,
+	}
+}
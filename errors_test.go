@@ -0,0 +1,65 @@
+// Copyright 2018 wyDay, LLC. All rights reserved.
+
+package turboactivate_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"golang.wyday.com/turboactivate"
+)
+
+func TestErrorIs(t *testing.T) {
+	var err error = &turboactivate.TAError{Code: turboactivate.TAERevoked, Func: "Activate", Message: "revoked"}
+
+	if !errors.Is(err, turboactivate.ErrRevoked) || errors.Is(err, turboactivate.ErrInet) {
+		t.Errorf("%v doesn't match only ErrRevoked", err)
+	}
+
+	// the wrapped errors still match
+	if wrapped := fmt.Errorf("activating: %w", err); !errors.Is(wrapped, turboactivate.ErrRevoked) {
+		t.Errorf("%v doesn't match ErrRevoked", wrapped)
+	}
+
+	// a TAError with Func only matches the errors of that function
+	if errors.Is(err, &turboactivate.TAError{Code: turboactivate.TAERevoked, Func: "IsGenuine"}) {
+		t.Error("the error of Activate matches the error of IsGenuine")
+	}
+
+	if !errors.Is(err, &turboactivate.TAError{Code: turboactivate.TAERevoked, Func: "Activate"}) {
+		t.Error("the error of Activate doesn't match the error of Activate")
+	}
+
+	var taErr *turboactivate.TAError
+
+	if !errors.As(fmt.Errorf("activating: %w", err), &taErr) || taErr.Code != turboactivate.TAERevoked || taErr.Func != "Activate" {
+		t.Errorf("errors.As() = %#v; want the TAError of Activate", taErr)
+	}
+}
+
+func TestSentinelErrors(t *testing.T) {
+	for _, e := range []*turboactivate.TAError{turboactivate.ErrFail, turboactivate.ErrInvalidPKey, turboactivate.ErrInet, turboactivate.ErrInetTLS} {
+		if e.Func != "" || e.Message == "" {
+			t.Errorf("%#v isn't a sentinel error", e)
+		}
+
+		if !errors.Is(e, e) {
+			t.Errorf("%v doesn't match itself", e)
+		}
+	}
+}
+
+func TestHRESULTString(t *testing.T) {
+	for hr, want := range map[turboactivate.HRESULT]string{
+		turboactivate.TAOK:           "TA_OK",
+		turboactivate.TAEInet:        "TA_E_INET",
+		turboactivate.TAEInetTLS:     "TA_E_INET_TLS",
+		turboactivate.HRESULT(0x7F):  "0x7F",
+		turboactivate.HRESULT(0x1AB): "0x1AB",
+	} {
+		if s := hr.String(); s != want {
+			t.Errorf("HRESULT(%d).String() = %q; want %q", uint32(hr), s, want)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+// Copyright 2018 wyDay, LLC. All rights reserved.
+
+package fake // import "golang.wyday.com/turboactivate/fake"
+
+import (
+	"sync"
+	"time"
+)
+
+// ManualClock is a clock that only moves when told to. Pass its Now
+// method to Backend.SetClock.
+type ManualClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewManualClock creates a ManualClock set to start.
+func NewManualClock(start time.Time) *ManualClock {
+	return &ManualClock{now: start}
+}
+
+// Now returns the current time of the clock.
+func (c *ManualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+// Advance moves the clock forward by d. A negative d turns the clock back,
+// which the fake backend treats as the system time being tampered with.
+func (c *ManualClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+}
+
+// AdvanceDays moves the clock forward by the number of days.
+func (c *ManualClock) AdvanceDays(days int) {
+	c.Advance(time.Duration(days) * day)
+}
+
+// Set sets the clock to t.
+func (c *ManualClock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = t
+}
@@ -0,0 +1,1357 @@
+// Copyright 2018 wyDay, LLC. All rights reserved.
+
+// Package fake provides an in-memory turboactivate.Backend for testing
+// the licensing paths of your app without the native TurboActivate library,
+// a LimeLM account, or an activated machine.
+//
+// The fake stores product keys, activations, trials, feature values and
+// extra data in memory, counts trial days and genuine checks with an
+// injectable clock, and lets you script the HRESULT any function returns:
+//
+//	b := fake.New()
+//	b.AddKey(guid, "AAAA-BBBB-CCCC-DDDD-EEEE-FFFF-GGGG", fake.Key{
+//		Features: map[string]string{"seats": "5"},
+//	})
+//	b.FailNext("IsGenuineEx", turboactivate.TAEInet)
+//
+//	ta, _ := turboactivate.NewTurboActivateWithBackend(b, guid, "")
+//
+// Build your tests with the "turboactivate_nonative" build tag (or with
+// CGO_ENABLED=0) to run them without TurboActivate.h and libTurboActivate.
+package fake // import "golang.wyday.com/turboactivate/fake"
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	"golang.wyday.com/turboactivate"
+)
+
+// Key is a product key that the fake "LimeLM servers" know about.
+type Key struct {
+	// Features are the custom license field values of the product key.
+	Features map[string]string
+
+	// MaxActivations is the maximum number of computers the product key
+	// can be activated on. 0 means unlimited.
+	MaxActivations int
+
+	// Activations is the number of computers the product key is
+	// already activated on (not counting this one).
+	Activations int
+
+	// Revoked marks the product key as revoked.
+	Revoked bool
+
+	// ForTurboFloat marks the product key as a TurboFloat Server key.
+	ForTurboFloat bool
+}
+
+// Product is the configuration of a product version (VersionGUID).
+type Product struct {
+	// TrialDays is the length of the trial in days. 0 means no trials are
+	// allowed (UseTrial returns TA_E_NO_MORE_TRIALS_ALLOWED for verified trials).
+	TrialDays uint32
+
+	// TrialExtensions maps the trial extensions that can be used with
+	// ExtendTrial to the number of days they add.
+	TrialExtensions map[string]uint32
+
+	// DisallowVM prevents activations inside a virtual machine.
+	DisallowVM bool
+}
+
+// DefaultTrialDays is the trial length of products that weren't added with AddProduct.
+const DefaultTrialDays = 30
+
+const day = 24 * time.Hour
+
+// product is the licensing state of a product on this "computer".
+type product struct {
+	guid   string
+	handle uint32
+	config Product
+
+	pkey             string
+	activated        bool
+	offlineActivated bool
+	extraData        string
+	lastVerified     time.Time
+	graceStart       time.Time
+	featuresChanged  bool
+
+	trialStarted  bool
+	trialVerified bool
+	trialStart    time.Time
+	trialExtDays  uint32
+	usedTrialExts map[string]bool
+
+	trialCallback func(status uint32)
+
+	// keys are the product keys of the product version (a product key
+	// isn't valid for the other products).
+	keys      map[string]*Key
+	blacklist map[string]bool
+}
+
+// offlineFile is the contents of the request files written by the fake.
+// The fake "LimeLM servers" accept a request file as its own response file.
+type offlineFile struct {
+	Type        string `json:"type"`
+	VersionGUID string `json:"version_guid"`
+	ProductKey  string `json:"product_key,omitempty"`
+	ExtraData   string `json:"extra_data,omitempty"`
+}
+
+// Backend is an in-memory turboactivate.Backend. Use New to create one.
+// It's safe for concurrent use.
+type Backend struct {
+	mu sync.Mutex
+
+	now      func() time.Time
+	lastSeen time.Time
+
+	online bool
+	inVM   bool
+	proxy  string
+
+	products   map[string]*product
+	handles    map[uint32]*product
+	nextHandle uint32
+
+	version [4]uint32
+
+	failures map[string][]turboactivate.HRESULT
+	always   map[string]turboactivate.HRESULT
+	calls    map[string]int
+
+	// pending are the trial callbacks to call once b.mu is unlocked.
+	pending []func()
+}
+
+// New creates an empty fake backend that's online, not in a VM and uses time.Now.
+func New() *Backend {
+	return &Backend{
+		now:      time.Now,
+		online:   true,
+		products: map[string]*product{},
+		handles:  map[uint32]*product{},
+		version:  [4]uint32{4, 4, 4, 0},
+		failures: map[string][]turboactivate.HRESULT{},
+		always:   map[string]turboactivate.HRESULT{},
+		calls:    map[string]int{},
+	}
+}
+
+// SetClock sets the function used to get the current time.
+// Use it with a ManualClock to test trial expiry and genuine re-checks.
+func (b *Backend) SetClock(now func() time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.now = now
+}
+
+// SetOnline sets whether the fake "LimeLM servers" can be reached. When
+// offline every function that contacts the servers returns TA_E_INET.
+func (b *Backend) SetOnline(online bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.online = online
+}
+
+// SetInVM sets whether the fake "computer" is a virtual machine.
+func (b *Backend) SetInVM(inVM bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.inVM = inVM
+}
+
+// AddProduct adds (or replaces) the configuration of a product version.
+func (b *Backend) AddProduct(versionGUID string, p Product) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.product(versionGUID).config = p
+}
+
+// AddKey adds a product key for the product version. Like the LimeLM product
+// keys, it's only valid for that VersionGUID: CheckAndSavePKey returns TA_FAIL
+// for it on the other products.
+func (b *Backend) AddKey(versionGUID string, productKey string, key Key) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	k := key
+	b.product(versionGUID).keys[productKey] = &k
+}
+
+// RevokeKey revokes the product key. The next time the servers are
+// contacted the product is deactivated.
+func (b *Backend) RevokeKey(productKey string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, p := range b.products {
+		if k, ok := p.keys[productKey]; ok {
+			k.Revoked = true
+		}
+	}
+}
+
+// FireTrialCallback calls the trial callback set for the VersionGUID (if any)
+// with the status, like the TurboActivate library does when it notices the
+// verified trial has expired (0x00, TA_CB_EXPIRED) or was tampered with
+// (0x01, TA_CB_EXPIRED_FRAUD). UseTrial calls it too.
+func (b *Backend) FireTrialCallback(versionGUID string, status uint32) {
+	b.mu.Lock()
+	defer b.unlock()
+
+	if p, ok := b.products[versionGUID]; ok {
+		b.notify(p, status)
+	}
+}
+
+// SetVersion sets the library version returned by GetVersion. Defaults to 4.4.4.0.
+func (b *Backend) SetVersion(major uint32, minor uint32, build uint32, revision uint32) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.version = [4]uint32{major, minor, build, revision}
+}
+
+// SetFeatures changes the custom license fields of the product key. The
+// next time the servers are contacted IsGenuine and IsGenuineEx return
+// TA_E_FEATURES_CHANGED.
+func (b *Backend) SetFeatures(productKey string, features map[string]string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, p := range b.products {
+		k, ok := p.keys[productKey]
+
+		if !ok {
+			continue
+		}
+
+		k.Features = features
+
+		if p.pkey == productKey && p.activated {
+			p.featuresChanged = true
+		}
+	}
+}
+
+// FailNext makes the next call to the Backend function funcName (e.g.
+// "Activate" or "IsGenuineEx") return ret. Calling it several times
+// queues the failures in order.
+func (b *Backend) FailNext(funcName string, ret turboactivate.HRESULT) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures[funcName] = append(b.failures[funcName], ret)
+}
+
+// Fail makes every call to the Backend function funcName return ret
+// until ClearFailures is called.
+func (b *Backend) Fail(funcName string, ret turboactivate.HRESULT) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.always[funcName] = ret
+}
+
+// ClearFailures removes all the failures set with Fail and FailNext.
+func (b *Backend) ClearFailures() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures = map[string][]turboactivate.HRESULT{}
+	b.always = map[string]turboactivate.HRESULT{}
+}
+
+// Calls returns how many times the Backend function funcName was called.
+func (b *Backend) Calls(funcName string) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.calls[funcName]
+}
+
+// Proxy returns the proxy set with SetCustomProxy.
+func (b *Backend) Proxy() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.proxy
+}
+
+// product gets or creates the state of the product version. b.mu must be held.
+func (b *Backend) product(versionGUID string) *product {
+	p, ok := b.products[versionGUID]
+
+	if !ok {
+		p = &product{
+			guid:          versionGUID,
+			config:        Product{TrialDays: DefaultTrialDays},
+			usedTrialExts: map[string]bool{},
+			keys:          map[string]*Key{},
+		}
+		b.products[versionGUID] = p
+	}
+
+	return p
+}
+
+// begin records the call to funcName and returns the scripted failure
+// for it, if any. b.mu must be held.
+func (b *Backend) begin(funcName string) (turboactivate.HRESULT, bool) {
+	b.calls[funcName]++
+
+	if q := b.failures[funcName]; len(q) > 0 {
+		b.failures[funcName] = q[1:]
+		return q[0], true
+	}
+
+	if ret, ok := b.always[funcName]; ok {
+		return ret, true
+	}
+
+	return turboactivate.TAOK, false
+}
+
+// clock returns the current time and whether the clock was turned back
+// since the last call. b.mu must be held.
+func (b *Backend) clock() (time.Time, bool) {
+	now := b.now()
+
+	if now.Before(b.lastSeen) {
+		return now, true
+	}
+
+	b.lastSeen = now
+	return now, false
+}
+
+// lookup gets the product for the handle. b.mu must be held.
+func (b *Backend) lookup(handle uint32) (*product, turboactivate.HRESULT) {
+	p, ok := b.handles[handle]
+
+	if !ok {
+		return nil, turboactivate.TAEInvalidHandle
+	}
+
+	return p, turboactivate.TAOK
+}
+
+// daysLeft rounds the time left up to whole days, like TurboActivate does.
+func daysLeft(left time.Duration) uint32 {
+	if left <= 0 {
+		return 0
+	}
+
+	return uint32((left + day - 1) / day)
+}
+
+// activate locks the product key to this computer. b.mu must be held.
+func (b *Backend) activate(p *product, productKey string, extraData string, now time.Time) turboactivate.HRESULT {
+	k, ok := p.keys[productKey]
+
+	if !ok {
+		return turboactivate.TAEPKey
+	}
+
+	if k.Revoked {
+		return turboactivate.TAERevoked
+	}
+
+	if p.config.DisallowVM && b.inVM {
+		return turboactivate.TAEInVM
+	}
+
+	if k.MaxActivations > 0 && k.Activations >= k.MaxActivations {
+		return turboactivate.TAEInUse
+	}
+
+	k.Activations++
+
+	p.pkey = productKey
+	p.activated = true
+	p.extraData = extraData
+	p.lastVerified = now
+	p.graceStart = time.Time{}
+	p.featuresChanged = false
+
+	return turboactivate.TAOK
+}
+
+// deactivate releases the activation. b.mu must be held.
+func (b *Backend) deactivate(p *product, eraseProductKey bool) {
+	if k, ok := p.keys[p.pkey]; ok && k.Activations > 0 {
+		k.Activations--
+	}
+
+	p.activated = false
+	p.offlineActivated = false
+	p.extraData = ""
+	p.graceStart = time.Time{}
+
+	if eraseProductKey {
+		p.pkey = ""
+	}
+}
+
+// verify contacts the fake servers for an activated product. b.mu must be held.
+func (b *Backend) verify(p *product, now time.Time) turboactivate.HRESULT {
+	if k, ok := p.keys[p.pkey]; !ok || k.Revoked {
+		b.deactivate(p, false)
+		return turboactivate.TAERevoked
+	}
+
+	p.lastVerified = now
+	p.graceStart = time.Time{}
+
+	if p.config.DisallowVM && b.inVM {
+		return turboactivate.TAEInVM
+	}
+
+	if p.featuresChanged {
+		p.featuresChanged = false
+		return turboactivate.TAEFeaturesChanged
+	}
+
+	return turboactivate.TAOK
+}
+
+func writeOfflineFile(filename string, f offlineFile) turboactivate.HRESULT {
+	data, err := json.Marshal(f)
+
+	if err != nil {
+		return turboactivate.TAFail
+	}
+
+	if err = os.WriteFile(filename, data, 0600); err != nil {
+		return turboactivate.TAEInvalidArgs
+	}
+
+	return turboactivate.TAOK
+}
+
+func readOfflineFile(filename string, typ string, versionGUID string) (offlineFile, turboactivate.HRESULT) {
+	var f offlineFile
+
+	data, err := os.ReadFile(filename)
+
+	if err != nil {
+		return f, turboactivate.TAEInvalidArgs
+	}
+
+	if err = json.Unmarshal(data, &f); err != nil || f.Type != typ || f.VersionGUID != versionGUID {
+		return f, turboactivate.TAFail
+	}
+
+	return f, turboactivate.TAOK
+}
+
+func validFlags(flags turboactivate.TAFlags) bool {
+	system := flags&turboactivate.TASystem != 0
+	user := flags&turboactivate.TAUser != 0
+
+	return system != user
+}
+
+// trialType checks the trial flags and returns whether it's a verified trial.
+func trialType(flags turboactivate.TAFlags) (bool, turboactivate.HRESULT) {
+	verified := flags&turboactivate.TAVerifiedTrial != 0
+	unverified := flags&turboactivate.TAUnverifiedTrial != 0
+
+	if verified == unverified {
+		return false, turboactivate.TAEMustSpecifyTrialType
+	}
+
+	return verified, turboactivate.TAOK
+}
+
+// PDetsFromPath implements turboactivate.Backend. The file isn't read.
+func (b *Backend) PDetsFromPath(filename string) turboactivate.HRESULT {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if ret, ok := b.begin("PDetsFromPath"); ok {
+		return ret
+	}
+
+	return turboactivate.TAOK
+}
+
+// PDetsFromByteArray implements turboactivate.Backend. The data isn't parsed.
+func (b *Backend) PDetsFromByteArray(data []byte) turboactivate.HRESULT {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if ret, ok := b.begin("PDetsFromByteArray"); ok {
+		return ret
+	}
+
+	if len(data) == 0 {
+		return turboactivate.TAEInvalidArgs
+	}
+
+	return turboactivate.TAOK
+}
+
+// GetHandle implements turboactivate.Backend.
+func (b *Backend) GetHandle(versionGUID string) uint32 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if ret, ok := b.begin("GetHandle"); ok && ret != turboactivate.TAOK {
+		return 0
+	}
+
+	if versionGUID == "" {
+		return 0
+	}
+
+	p := b.product(versionGUID)
+
+	if p.handle == 0 {
+		b.nextHandle++
+		p.handle = b.nextHandle
+		b.handles[p.handle] = p
+	}
+
+	return p.handle
+}
+
+// Activate implements turboactivate.Backend.
+func (b *Backend) Activate(handle uint32, extraData string) turboactivate.HRESULT {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if ret, ok := b.begin("Activate"); ok {
+		return ret
+	}
+
+	p, ret := b.lookup(handle)
+
+	if ret != turboactivate.TAOK {
+		return ret
+	}
+
+	if len(extraData) > 255 {
+		return turboactivate.TAEEDataLong
+	}
+
+	if p.pkey == "" {
+		return turboactivate.TAEPKey
+	}
+
+	if p.activated {
+		return turboactivate.TAOK
+	}
+
+	if !b.online {
+		return turboactivate.TAEInet
+	}
+
+	now, _ := b.clock()
+
+	return b.activate(p, p.pkey, extraData, now)
+}
+
+// ActivationRequestToFile implements turboactivate.Backend. The request
+// file can be passed straight to ActivateFromFile.
+func (b *Backend) ActivationRequestToFile(handle uint32, filename string, extraData string) turboactivate.HRESULT {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if ret, ok := b.begin("ActivationRequestToFile"); ok {
+		return ret
+	}
+
+	p, ret := b.lookup(handle)
+
+	if ret != turboactivate.TAOK {
+		return ret
+	}
+
+	if len(extraData) > 255 {
+		return turboactivate.TAEEDataLong
+	}
+
+	if p.pkey == "" {
+		return turboactivate.TAEPKey
+	}
+
+	return writeOfflineFile(filename, offlineFile{
+		Type:        "activation",
+		VersionGUID: p.guid,
+		ProductKey:  p.pkey,
+		ExtraData:   extraData,
+	})
+}
+
+// ActivateFromFile implements turboactivate.Backend.
+func (b *Backend) ActivateFromFile(handle uint32, filename string) turboactivate.HRESULT {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if ret, ok := b.begin("ActivateFromFile"); ok {
+		return ret
+	}
+
+	p, ret := b.lookup(handle)
+
+	if ret != turboactivate.TAOK {
+		return ret
+	}
+
+	f, ret := readOfflineFile(filename, "activation", p.guid)
+
+	if ret != turboactivate.TAOK {
+		return ret
+	}
+
+	if p.activated {
+		return turboactivate.TAEAlreadyActivated
+	}
+
+	now, _ := b.clock()
+
+	ret = b.activate(p, f.ProductKey, f.ExtraData, now)
+
+	if ret == turboactivate.TAOK {
+		p.offlineActivated = true
+	}
+
+	return ret
+}
+
+// CheckAndSavePKey implements turboactivate.Backend.
+func (b *Backend) CheckAndSavePKey(handle uint32, productKey string, flags turboactivate.TAFlags) turboactivate.HRESULT {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if ret, ok := b.begin("CheckAndSavePKey"); ok {
+		return ret
+	}
+
+	p, ret := b.lookup(handle)
+
+	if ret != turboactivate.TAOK {
+		return ret
+	}
+
+	if !validFlags(flags) {
+		return turboactivate.TAEInvalidFlags
+	}
+
+	if p.activated && p.pkey != productKey {
+		return turboactivate.TAEAlreadyActivated
+	}
+
+	k, ok := p.keys[productKey]
+
+	if !ok || p.blacklist[productKey] {
+		return turboactivate.TAFail
+	}
+
+	if k.ForTurboFloat {
+		return turboactivate.TAEKeyForTurboFloat
+	}
+
+	p.pkey = productKey
+
+	return turboactivate.TAOK
+}
+
+// Deactivate implements turboactivate.Backend.
+func (b *Backend) Deactivate(handle uint32, eraseProductKey bool) turboactivate.HRESULT {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if ret, ok := b.begin("Deactivate"); ok {
+		return ret
+	}
+
+	p, ret := b.lookup(handle)
+
+	if ret != turboactivate.TAOK {
+		return ret
+	}
+
+	if p.pkey == "" {
+		return turboactivate.TAEPKey
+	}
+
+	if !p.activated {
+		return turboactivate.TAEActivate
+	}
+
+	if !b.online {
+		return turboactivate.TAEInet
+	}
+
+	b.deactivate(p, eraseProductKey)
+
+	return turboactivate.TAOK
+}
+
+// DeactivationRequestToFile implements turboactivate.Backend.
+func (b *Backend) DeactivationRequestToFile(handle uint32, filename string, eraseProductKey bool) turboactivate.HRESULT {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if ret, ok := b.begin("DeactivationRequestToFile"); ok {
+		return ret
+	}
+
+	p, ret := b.lookup(handle)
+
+	if ret != turboactivate.TAOK {
+		return ret
+	}
+
+	if p.pkey == "" {
+		return turboactivate.TAEPKey
+	}
+
+	if !p.activated {
+		return turboactivate.TAEActivate
+	}
+
+	ret = writeOfflineFile(filename, offlineFile{
+		Type:        "deactivation",
+		VersionGUID: p.guid,
+		ProductKey:  p.pkey,
+	})
+
+	if ret == turboactivate.TAOK {
+		b.deactivate(p, eraseProductKey)
+	}
+
+	return ret
+}
+
+// GetExtraData implements turboactivate.Backend.
+func (b *Backend) GetExtraData(handle uint32) (string, turboactivate.HRESULT) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if ret, ok := b.begin("GetExtraData"); ok {
+		return "", ret
+	}
+
+	p, ret := b.lookup(handle)
+
+	if ret != turboactivate.TAOK {
+		return "", ret
+	}
+
+	return p.extraData, turboactivate.TAOK
+}
+
+// GetFeatureValue implements turboactivate.Backend.
+func (b *Backend) GetFeatureValue(handle uint32, featureName string) (string, turboactivate.HRESULT) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if ret, ok := b.begin("GetFeatureValue"); ok {
+		return "", ret
+	}
+
+	p, ret := b.lookup(handle)
+
+	if ret != turboactivate.TAOK {
+		return "", ret
+	}
+
+	k, ok := p.keys[p.pkey]
+
+	if !ok {
+		return "", turboactivate.TAEPKey
+	}
+
+	value, ok := k.Features[featureName]
+
+	if !ok {
+		return "", turboactivate.TAFail
+	}
+
+	return value, turboactivate.TAOK
+}
+
+// GetPKey implements turboactivate.Backend.
+func (b *Backend) GetPKey(handle uint32) (string, turboactivate.HRESULT) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if ret, ok := b.begin("GetPKey"); ok {
+		return "", ret
+	}
+
+	p, ret := b.lookup(handle)
+
+	if ret != turboactivate.TAOK {
+		return "", ret
+	}
+
+	if p.pkey == "" {
+		return "", turboactivate.TAEPKey
+	}
+
+	return p.pkey, turboactivate.TAOK
+}
+
+// IsActivated implements turboactivate.Backend.
+func (b *Backend) IsActivated(handle uint32) turboactivate.HRESULT {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if ret, ok := b.begin("IsActivated"); ok {
+		return ret
+	}
+
+	p, ret := b.lookup(handle)
+
+	if ret != turboactivate.TAOK {
+		return ret
+	}
+
+	if !p.activated {
+		return turboactivate.TAFail
+	}
+
+	return turboactivate.TAOK
+}
+
+// IsDateValid implements turboactivate.Backend. It returns TA_E_EXPIRED
+// if the clock was turned back.
+func (b *Backend) IsDateValid(handle uint32, dateTime string, flags turboactivate.TADateCheckFlags) turboactivate.HRESULT {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if ret, ok := b.begin("IsDateValid"); ok {
+		return ret
+	}
+
+	if _, ret := b.lookup(handle); ret != turboactivate.TAOK {
+		return ret
+	}
+
+	date, err := time.Parse("2006-01-02 15:04:05", dateTime)
+
+	if err != nil || flags&^turboactivate.TAHasNotExpired != 0 {
+		return turboactivate.TAEInvalidArgs
+	}
+
+	now, tampered := b.clock()
+
+	if tampered {
+		return turboactivate.TAEExpired
+	}
+
+	if flags&turboactivate.TAHasNotExpired != 0 && now.After(date) {
+		return turboactivate.TAFail
+	}
+
+	return turboactivate.TAOK
+}
+
+// IsGenuine implements turboactivate.Backend.
+func (b *Backend) IsGenuine(handle uint32) turboactivate.HRESULT {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if ret, ok := b.begin("IsGenuine"); ok {
+		return ret
+	}
+
+	p, ret := b.lookup(handle)
+
+	if ret != turboactivate.TAOK {
+		return ret
+	}
+
+	if !p.activated {
+		return turboactivate.TAEActivate
+	}
+
+	if !b.online {
+		return turboactivate.TAEInet
+	}
+
+	now, _ := b.clock()
+
+	return b.verify(p, now)
+}
+
+// IsGenuineEx implements turboactivate.Backend. The servers are contacted
+// once daysBetweenChecks days have passed since the last verification. If
+// they can't be reached the grace period starts, and once
+// graceDaysOnInetErr days have passed the product is deactivated.
+func (b *Backend) IsGenuineEx(handle uint32, daysBetweenChecks uint32, graceDaysOnInetErr uint32, skipOffline bool, offlineShowInetErr bool) turboactivate.HRESULT {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if ret, ok := b.begin("IsGenuineEx"); ok {
+		return ret
+	}
+
+	p, ret := b.lookup(handle)
+
+	if ret != turboactivate.TAOK {
+		return ret
+	}
+
+	if !p.activated {
+		return turboactivate.TAEActivate
+	}
+
+	now, _ := b.clock()
+
+	if now.Sub(p.lastVerified) < time.Duration(daysBetweenChecks)*day {
+		return turboactivate.TAOK
+	}
+
+	if skipOffline && p.offlineActivated {
+		if offlineShowInetErr {
+			return turboactivate.TAEInetDelayed
+		}
+
+		return turboactivate.TAOK
+	}
+
+	if b.online {
+		return b.verify(p, now)
+	}
+
+	if p.graceStart.IsZero() {
+		p.graceStart = now
+	}
+
+	if now.Sub(p.graceStart) < time.Duration(graceDaysOnInetErr)*day {
+		return turboactivate.TAEInetDelayed
+	}
+
+	b.deactivate(p, false)
+
+	return turboactivate.TAFail
+}
+
+// GenuineDays implements turboactivate.Backend.
+func (b *Backend) GenuineDays(handle uint32, daysBetweenChecks uint32, graceDaysOnInetErr uint32) (uint32, bool, turboactivate.HRESULT) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if ret, ok := b.begin("GenuineDays"); ok {
+		return 0, false, ret
+	}
+
+	p, ret := b.lookup(handle)
+
+	if ret != turboactivate.TAOK {
+		return 0, false, ret
+	}
+
+	if !p.activated {
+		return 0, false, turboactivate.TAEActivate
+	}
+
+	now, _ := b.clock()
+
+	if !p.graceStart.IsZero() {
+		return daysLeft(p.graceStart.Add(time.Duration(graceDaysOnInetErr) * day).Sub(now)), true, turboactivate.TAOK
+	}
+
+	return daysLeft(p.lastVerified.Add(time.Duration(daysBetweenChecks) * day).Sub(now)), false, turboactivate.TAOK
+}
+
+// IsProductKeyValid implements turboactivate.Backend.
+func (b *Backend) IsProductKeyValid(handle uint32) turboactivate.HRESULT {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if ret, ok := b.begin("IsProductKeyValid"); ok {
+		return ret
+	}
+
+	p, ret := b.lookup(handle)
+
+	if ret != turboactivate.TAOK {
+		return ret
+	}
+
+	if _, ok := p.keys[p.pkey]; !ok || p.blacklist[p.pkey] {
+		return turboactivate.TAFail
+	}
+
+	return turboactivate.TAOK
+}
+
+// SetCustomProxy implements turboactivate.Backend.
+func (b *Backend) SetCustomProxy(proxy string) turboactivate.HRESULT {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if ret, ok := b.begin("SetCustomProxy"); ok {
+		return ret
+	}
+
+	b.proxy = proxy
+
+	return turboactivate.TAOK
+}
+
+// TrialDaysRemaining implements turboactivate.Backend.
+func (b *Backend) TrialDaysRemaining(handle uint32, flags turboactivate.TAFlags) (uint32, turboactivate.HRESULT) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if ret, ok := b.begin("TrialDaysRemaining"); ok {
+		return 0, ret
+	}
+
+	p, ret := b.lookup(handle)
+
+	if ret != turboactivate.TAOK {
+		return 0, ret
+	}
+
+	verified, ret := trialType(flags)
+
+	if ret != turboactivate.TAOK {
+		return 0, ret
+	}
+
+	if !p.trialStarted {
+		return 0, turboactivate.TAEMustUseTrial
+	}
+
+	if p.trialVerified && !verified {
+		return 0, turboactivate.TAEAlreadyVerifiedTrial
+	}
+
+	now, _ := b.clock()
+
+	return daysLeft(b.trialEnd(p).Sub(now)), turboactivate.TAOK
+}
+
+func (b *Backend) trialEnd(p *product) time.Time {
+	return p.trialStart.Add(time.Duration(p.config.TrialDays+p.trialExtDays) * day)
+}
+
+// notify queues a call to the trial callback of the product (if any) with
+// the status. b.mu must be held.
+func (b *Backend) notify(p *product, status uint32) {
+	if callback := p.trialCallback; callback != nil {
+		b.pending = append(b.pending, func() { callback(status) })
+	}
+}
+
+// unlock unlocks b.mu, then calls the trial callbacks queued while it was
+// held, so they can call back into the backend.
+func (b *Backend) unlock() {
+	var pending = b.pending
+	b.pending = nil
+	b.mu.Unlock()
+
+	for _, callback := range pending {
+		callback()
+	}
+}
+
+// startTrial starts the trial (if it hasn't been started) and checks it. b.mu must be held.
+func (b *Backend) startTrial(p *product, verified bool) turboactivate.HRESULT {
+	now, tampered := b.clock()
+
+	if !p.trialStarted {
+		if verified && p.config.TrialDays == 0 {
+			return turboactivate.TAENoMoreTrialsAllowed
+		}
+
+		p.trialStarted = true
+		p.trialStart = now
+	}
+
+	if verified {
+		p.trialVerified = true
+	}
+
+	if tampered {
+		if verified {
+			b.notify(p, trialCBExpiredFraud)
+		}
+
+		return turboactivate.TAETrial
+	}
+
+	if !now.Before(b.trialEnd(p)) {
+		if verified {
+			b.notify(p, trialCBExpired)
+		}
+
+		return turboactivate.TAETrialExpired
+	}
+
+	return turboactivate.TAOK
+}
+
+// UseTrial implements turboactivate.Backend. Returns TA_E_TRIAL if the
+// clock was turned back.
+func (b *Backend) UseTrial(handle uint32, flags turboactivate.TAFlags, extraData string) turboactivate.HRESULT {
+	b.mu.Lock()
+	defer b.unlock()
+
+	if ret, ok := b.begin("UseTrial"); ok {
+		return ret
+	}
+
+	p, ret := b.lookup(handle)
+
+	if ret != turboactivate.TAOK {
+		return ret
+	}
+
+	if !validFlags(flags) {
+		return turboactivate.TAEInvalidFlags
+	}
+
+	verified, ret := trialType(flags)
+
+	if ret != turboactivate.TAOK {
+		return ret
+	}
+
+	if p.trialVerified && !verified {
+		return turboactivate.TAEAlreadyVerifiedTrial
+	}
+
+	if len(extraData) > 255 {
+		return turboactivate.TAEEDataLong
+	}
+
+	if flags&turboactivate.TADisallowVM != 0 && b.inVM {
+		return turboactivate.TAEInVM
+	}
+
+	if verified && !p.trialStarted && !b.online {
+		return turboactivate.TAEInet
+	}
+
+	return b.startTrial(p, verified)
+}
+
+// UseTrialVerifiedRequest implements turboactivate.Backend. The request
+// file can be passed straight to UseTrialVerifiedFromFile.
+func (b *Backend) UseTrialVerifiedRequest(handle uint32, filename string, extraData string) turboactivate.HRESULT {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if ret, ok := b.begin("UseTrialVerifiedRequest"); ok {
+		return ret
+	}
+
+	p, ret := b.lookup(handle)
+
+	if ret != turboactivate.TAOK {
+		return ret
+	}
+
+	if len(extraData) > 255 {
+		return turboactivate.TAEEDataLong
+	}
+
+	return writeOfflineFile(filename, offlineFile{
+		Type:        "trial",
+		VersionGUID: p.guid,
+		ExtraData:   extraData,
+	})
+}
+
+// UseTrialVerifiedFromFile implements turboactivate.Backend.
+func (b *Backend) UseTrialVerifiedFromFile(handle uint32, filename string, flags turboactivate.TAFlags) turboactivate.HRESULT {
+	b.mu.Lock()
+	defer b.unlock()
+
+	if ret, ok := b.begin("UseTrialVerifiedFromFile"); ok {
+		return ret
+	}
+
+	p, ret := b.lookup(handle)
+
+	if ret != turboactivate.TAOK {
+		return ret
+	}
+
+	if !validFlags(flags) {
+		return turboactivate.TAEInvalidFlags
+	}
+
+	if _, ret = readOfflineFile(filename, "trial", p.guid); ret != turboactivate.TAOK {
+		return ret
+	}
+
+	return b.startTrial(p, true)
+}
+
+// ExtendTrial implements turboactivate.Backend.
+func (b *Backend) ExtendTrial(handle uint32, flags turboactivate.TAFlags, trialExtension string) turboactivate.HRESULT {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if ret, ok := b.begin("ExtendTrial"); ok {
+		return ret
+	}
+
+	p, ret := b.lookup(handle)
+
+	if ret != turboactivate.TAOK {
+		return ret
+	}
+
+	verified, ret := trialType(flags)
+
+	if ret != turboactivate.TAOK {
+		return ret
+	}
+
+	if !p.trialStarted {
+		return turboactivate.TAEMustUseTrial
+	}
+
+	if verified && !b.online {
+		return turboactivate.TAEInet
+	}
+
+	days, ok := p.config.TrialExtensions[trialExtension]
+
+	if !ok {
+		return turboactivate.TAFail
+	}
+
+	if p.usedTrialExts[trialExtension] {
+		return turboactivate.TAETrialEUsed
+	}
+
+	p.usedTrialExts[trialExtension] = true
+	p.trialExtDays += days
+
+	return turboactivate.TAOK
+}
+
+// SetCustomActDataPath implements turboactivate.Backend.
+func (b *Backend) SetCustomActDataPath(handle uint32, directory string) turboactivate.HRESULT {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if ret, ok := b.begin("SetCustomActDataPath"); ok {
+		return ret
+	}
+
+	if _, ret := b.lookup(handle); ret != turboactivate.TAOK {
+		return ret
+	}
+
+	if directory == "" {
+		return turboactivate.TAEInvalidArgs
+	}
+
+	return turboactivate.TAOK
+}
+
+// The statuses passed to the trial callback.
+const (
+	trialCBExpired      = 0x00 // TA_CB_EXPIRED
+	trialCBExpiredFraud = 0x01 // TA_CB_EXPIRED_FRAUD
+)
+
+// SetTrialCallback implements turboactivate.Backend. The callback is called
+// by UseTrial when the verified trial has expired or was tampered with, and
+// by FireTrialCallback.
+func (b *Backend) SetTrialCallback(handle uint32, callback func(status uint32)) turboactivate.HRESULT {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if ret, ok := b.begin("SetTrialCallback"); ok {
+		return ret
+	}
+
+	p, ret := b.lookup(handle)
+
+	if ret != turboactivate.TAOK {
+		return ret
+	}
+
+	p.trialCallback = callback
+
+	return turboactivate.TAOK
+}
+
+// GetVersion implements turboactivate.Backend.
+func (b *Backend) GetVersion() (uint32, uint32, uint32, uint32, turboactivate.HRESULT) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if ret, ok := b.begin("GetVersion"); ok {
+		return 0, 0, 0, 0, ret
+	}
+
+	return b.version[0], b.version[1], b.version[2], b.version[3], turboactivate.TAOK
+}
+
+// Cleanup implements turboactivate.Backend. It invalidates every handle, but
+// keeps the licensing state (like the TurboActivate library keeps it on disk).
+func (b *Backend) Cleanup() turboactivate.HRESULT {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if ret, ok := b.begin("Cleanup"); ok {
+		return ret
+	}
+
+	for _, p := range b.products {
+		p.handle = 0
+		p.trialCallback = nil
+	}
+
+	b.handles = map[uint32]*product{}
+
+	return turboactivate.TAOK
+}
+
+// BlackListKeys implements turboactivate.Backend. CheckAndSavePKey rejects
+// the blacklisted keys.
+func (b *Backend) BlackListKeys(handle uint32, keys []string) turboactivate.HRESULT {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if ret, ok := b.begin("BlackListKeys"); ok {
+		return ret
+	}
+
+	p, ret := b.lookup(handle)
+
+	if ret != turboactivate.TAOK {
+		return ret
+	}
+
+	if len(keys) == 0 {
+		return turboactivate.TAEInvalidArgs
+	}
+
+	if p.blacklist == nil {
+		p.blacklist = map[string]bool{}
+	}
+
+	for _, key := range keys {
+		p.blacklist[key] = true
+	}
+
+	return turboactivate.TAOK
+}
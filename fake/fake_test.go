@@ -0,0 +1,207 @@
+// Copyright 2018 wyDay, LLC. All rights reserved.
+
+package fake_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"golang.wyday.com/turboactivate"
+	"golang.wyday.com/turboactivate/fake"
+)
+
+const (
+	testGUID = "18324776654b3946fc44a5f3.49025204"
+	testPKey = "AAAA-BBBB-CCCC-DDDD-EEEE-FFFF-GGGG"
+)
+
+var trialFlags = turboactivate.TAUser | turboactivate.TAVerifiedTrial
+
+// within fails the test if f doesn't return soon (e.g. because it deadlocked).
+func within(t *testing.T, f func()) {
+	t.Helper()
+
+	var done = make(chan struct{})
+
+	go func() {
+		defer close(done)
+		f()
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("deadlock")
+	}
+}
+
+func TestTrialCallbackCanCallBackend(t *testing.T) {
+	b := fake.New()
+	b.AddProduct(testGUID, fake.Product{TrialDays: 1})
+
+	var clock = fake.NewManualClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	b.SetClock(clock.Now)
+
+	var h = b.GetHandle(testGUID)
+	var statuses []uint32
+
+	b.SetTrialCallback(h, func(status uint32) {
+		// calling back into the backend must not deadlock
+		b.TrialDaysRemaining(h, trialFlags)
+		statuses = append(statuses, status)
+	})
+
+	if ret := b.UseTrial(h, trialFlags, ""); ret != turboactivate.TAOK {
+		t.Fatalf("UseTrial() = %v", ret)
+	}
+
+	if days, ret := b.TrialDaysRemaining(h, trialFlags); days != 1 || ret != turboactivate.TAOK {
+		t.Errorf("TrialDaysRemaining() = %d, %v; want 1", days, ret)
+	}
+
+	clock.Advance(25 * time.Hour)
+
+	within(t, func() {
+		if ret := b.UseTrial(h, trialFlags, ""); ret != turboactivate.TAETrialExpired {
+			t.Errorf("UseTrial() after the trial = %v; want TA_E_TRIAL_EXPIRED", ret)
+		}
+	})
+
+	// turning the clock back is fraud
+	clock.Advance(-time.Hour)
+
+	within(t, func() {
+		if ret := b.UseTrial(h, trialFlags, ""); ret != turboactivate.TAETrial {
+			t.Errorf("UseTrial() after turning the clock back = %v; want TA_E_TRIAL", ret)
+		}
+	})
+
+	within(t, func() { b.FireTrialCallback(testGUID, 0x00) })
+
+	if want := []uint32{0x00, 0x01, 0x00}; len(statuses) != len(want) || statuses[0] != want[0] || statuses[1] != want[1] || statuses[2] != want[2] {
+		t.Errorf("callback statuses = %v; want %v", statuses, want)
+	}
+}
+
+func TestScriptedFailures(t *testing.T) {
+	b := fake.New()
+	b.AddKey(testGUID, testPKey, fake.Key{})
+
+	var h = b.GetHandle(testGUID)
+
+	b.FailNext("CheckAndSavePKey", turboactivate.TAEInvalidFlags)
+
+	if ret := b.CheckAndSavePKey(h, testPKey, turboactivate.TAUser); ret != turboactivate.TAEInvalidFlags {
+		t.Errorf("CheckAndSavePKey() = %v; want the scripted failure", ret)
+	}
+
+	if ret := b.CheckAndSavePKey(h, testPKey, turboactivate.TAUser); ret != turboactivate.TAOK {
+		t.Errorf("CheckAndSavePKey() = %v; want TA_OK", ret)
+	}
+
+	if n := b.Calls("CheckAndSavePKey"); n != 2 {
+		t.Errorf("Calls() = %d; want 2", n)
+	}
+
+	b.Fail("Activate", turboactivate.TAEInet)
+
+	for i := 0; i < 2; i++ {
+		if ret := b.Activate(h, ""); ret != turboactivate.TAEInet {
+			t.Errorf("Activate() = %v; want TA_E_INET", ret)
+		}
+	}
+
+	b.ClearFailures()
+
+	if ret := b.Activate(h, ""); ret != turboactivate.TAOK {
+		t.Errorf("Activate() = %v; want TA_OK", ret)
+	}
+}
+
+func TestActivationsAndRevocation(t *testing.T) {
+	b := fake.New()
+	b.AddKey(testGUID, testPKey, fake.Key{MaxActivations: 1, Activations: 1})
+
+	var h = b.GetHandle(testGUID)
+	b.CheckAndSavePKey(h, testPKey, turboactivate.TAUser)
+
+	if ret := b.Activate(h, ""); ret != turboactivate.TAEInUse {
+		t.Errorf("Activate() = %v; want TA_E_IN_USE", ret)
+	}
+
+	b.AddKey(testGUID, testPKey, fake.Key{})
+
+	if ret := b.Activate(h, "extra"); ret != turboactivate.TAOK {
+		t.Fatalf("Activate() = %v", ret)
+	}
+
+	if extra, ret := b.GetExtraData(h); extra != "extra" || ret != turboactivate.TAOK {
+		t.Errorf("GetExtraData() = %q, %v", extra, ret)
+	}
+
+	b.RevokeKey(testPKey)
+
+	if ret := b.IsGenuine(h); ret != turboactivate.TAERevoked {
+		t.Errorf("IsGenuine() = %v; want TA_E_REVOKED", ret)
+	}
+
+	if ret := b.IsActivated(h); ret != turboactivate.TAFail {
+		t.Errorf("IsActivated() after revoking = %v; want TA_FAIL", ret)
+	}
+}
+
+func TestKeysOfOtherProducts(t *testing.T) {
+	const otherGUID = "28324776654b3946fc44a5f3.49025205"
+
+	b := fake.New()
+	b.AddKey(testGUID, testPKey, fake.Key{})
+
+	var h = b.GetHandle(testGUID)
+	var other = b.GetHandle(otherGUID)
+
+	if ret := b.CheckAndSavePKey(other, testPKey, turboactivate.TAUser); ret != turboactivate.TAFail {
+		t.Errorf("CheckAndSavePKey() of the key of another product = %v; want TA_FAIL", ret)
+	}
+
+	if ret := b.CheckAndSavePKey(h, testPKey, turboactivate.TAUser); ret != turboactivate.TAOK {
+		t.Fatalf("CheckAndSavePKey() = %v", ret)
+	}
+
+	if ret := b.IsProductKeyValid(h); ret != turboactivate.TAOK {
+		t.Errorf("IsProductKeyValid() = %v; want TA_OK", ret)
+	}
+
+	b.BlackListKeys(h, []string{testPKey})
+
+	if ret := b.IsProductKeyValid(h); ret != turboactivate.TAFail {
+		t.Errorf("IsProductKeyValid() of a blacklisted key = %v; want TA_FAIL", ret)
+	}
+}
+
+func TestOfflineFiles(t *testing.T) {
+	b := fake.New()
+	b.AddKey(testGUID, testPKey, fake.Key{})
+	b.SetOnline(false)
+
+	var h = b.GetHandle(testGUID)
+	b.CheckAndSavePKey(h, testPKey, turboactivate.TAUser)
+
+	var req = filepath.Join(t.TempDir(), "request.xml")
+
+	if ret := b.ActivationRequestToFile(h, req, "offline"); ret != turboactivate.TAOK {
+		t.Fatalf("ActivationRequestToFile() = %v", ret)
+	}
+
+	if ret := b.ActivateFromFile(h, filepath.Join(t.TempDir(), "missing.xml")); ret == turboactivate.TAOK {
+		t.Error("ActivateFromFile() of a missing file succeeded")
+	}
+
+	if ret := b.ActivateFromFile(h, req); ret != turboactivate.TAOK {
+		t.Fatalf("ActivateFromFile() = %v", ret)
+	}
+
+	if ret := b.IsActivated(h); ret != turboactivate.TAOK {
+		t.Errorf("IsActivated() = %v; want TA_OK", ret)
+	}
+}
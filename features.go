@@ -0,0 +1,396 @@
+// Copyright 2018 wyDay, LLC. All rights reserved.
+
+package turboactivate // import "golang.wyday.com/turboactivate"
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrFeatureMissing is used in a FeatureError when a required feature
+// (custom license field) doesn't exist.
+var ErrFeatureMissing = errors.New("The feature is missing")
+
+// FeatureError describes a feature (custom license field) that's missing
+// or couldn't be parsed.
+type FeatureError struct {
+	// Feature is the name of the custom license field.
+	Feature string
+
+	// Field is the name of the struct field (only set by DecodeFeatures).
+	Field string
+
+	// Value is the raw value of the custom license field.
+	Value string
+
+	// Err is the underlying error.
+	Err error
+}
+
+// Error returns the description of the error.
+func (e *FeatureError) Error() string {
+	var msg = "feature \"" + e.Feature + "\""
+
+	if e.Field != "" {
+		msg += " (field " + e.Field + ")"
+	}
+
+	if e.Err == ErrFeatureMissing {
+		return msg + " is required but missing"
+	}
+
+	if e.Value != "" {
+		msg += " has invalid value \"" + e.Value + "\""
+	}
+
+	return msg + ": " + e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *FeatureError) Unwrap() error {
+	return e.Err
+}
+
+// FeatureErrors is the list of errors returned by DecodeFeatures.
+type FeatureErrors []*FeatureError
+
+// Error returns the description of every error.
+func (e FeatureErrors) Error() string {
+	var msgs = make([]string, len(e))
+
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+
+	return strings.Join(msgs, "; ")
+}
+
+// featureTimeLayouts are the date formats accepted for date features.
+// The first is the format LimeLM uses for date custom license fields.
+var featureTimeLayouts = []string{
+	DateTimeLayout,
+	"2006-01-02",
+	time.RFC3339,
+}
+
+func parseFeatureInt(value string) (int64, error) {
+	return strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+}
+
+func parseFeatureUint(value string) (uint64, error) {
+	return strconv.ParseUint(strings.TrimSpace(value), 10, 64)
+}
+
+func parseFeatureFloat(value string) (float64, error) {
+	return strconv.ParseFloat(strings.TrimSpace(value), 64)
+}
+
+func parseFeatureBool(value string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "1", "t", "true", "y", "yes", "on":
+		return true, nil
+	case "0", "f", "false", "n", "no", "off", "":
+		return false, nil
+	default:
+		return false, errors.New("not a boolean")
+	}
+}
+
+func parseFeatureTime(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+
+	for _, layout := range featureTimeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.UTC(), nil
+		}
+	}
+
+	return time.Time{}, errors.New("not a date in the \"YYYY-MM-DD HH:mm:ss\" format")
+}
+
+func parseFeatureStrings(value string) []string {
+	var list []string
+
+	for _, s := range strings.Split(value, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			list = append(list, s)
+		}
+	}
+
+	return list
+}
+
+// GetFeatureInt gets the value of a custom license field as an integer.
+func (ta *TurboActivate) GetFeatureInt(featureName string) (int64, error) {
+	value, err := ta.GetFeatureValue(featureName)
+
+	if err != nil {
+		return 0, err
+	}
+
+	i, err := parseFeatureInt(value)
+
+	if err != nil {
+		return 0, &FeatureError{Feature: featureName, Value: value, Err: err}
+	}
+
+	return i, nil
+}
+
+// GetFeatureBool gets the value of a custom license field as a boolean.
+// "1", "true", "yes" and "on" are true, "0", "false", "no", "off" and
+// an empty value are false (case-insensitive).
+func (ta *TurboActivate) GetFeatureBool(featureName string) (bool, error) {
+	value, err := ta.GetFeatureValue(featureName)
+
+	if err != nil {
+		return false, err
+	}
+
+	b, err := parseFeatureBool(value)
+
+	if err != nil {
+		return false, &FeatureError{Feature: featureName, Value: value, Err: err}
+	}
+
+	return b, nil
+}
+
+// GetFeatureTime gets the value of a date custom license field. The value
+// must be a UTC date in the "YYYY-MM-DD HH:mm:ss" or "YYYY-MM-DD" format,
+// or an RFC 3339 date.
+func (ta *TurboActivate) GetFeatureTime(featureName string) (time.Time, error) {
+	value, err := ta.GetFeatureValue(featureName)
+
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	t, err := parseFeatureTime(value)
+
+	if err != nil {
+		return time.Time{}, &FeatureError{Feature: featureName, Value: value, Err: err}
+	}
+
+	return t, nil
+}
+
+// IsFeatureTimeValid reads the date custom license field (see GetFeatureTime)
+// and checks it with IsTimeValid().
+func (ta *TurboActivate) IsFeatureTimeValid(featureName string, flags TADateCheckFlags) (bool, error) {
+	t, err := ta.GetFeatureTime(featureName)
+
+	if err != nil {
+		return false, err
+	}
+
+	return ta.IsTimeValid(t, flags)
+}
+
+// FeatureNotExpired returns whether the date in the custom license field
+// (e.g. "update_expires") hasn't passed yet. Like IsDateValid() with the
+// TAHasNotExpired flag, it checks the date against the time TurboActivate
+// trusts, not just the system clock (which can be turned back).
+//
+// A date without a time (e.g. "2026-01-31") expires at the start of that day (UTC).
+func (ta *TurboActivate) FeatureNotExpired(featureName string) (bool, error) {
+	return ta.IsFeatureTimeValid(featureName, TAHasNotExpired)
+}
+
+// GetFeatureStrings gets the value of a custom license field as a comma
+// separated list. The items are trimmed and empty items are dropped.
+func (ta *TurboActivate) GetFeatureStrings(featureName string) ([]string, error) {
+	value, err := ta.GetFeatureValue(featureName)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return parseFeatureStrings(value), nil
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// DecodeFeatures fills the struct pointed to by v with the values of the
+// custom license fields named in the `ta` tags of the struct fields:
+//
+//	type Features struct {
+//		Seats   int       `ta:"seats,default=1"`
+//		Updates time.Time `ta:"update_expires,required"`
+//		Modules []string  `ta:"modules"`
+//	}
+//
+// The supported field types are string, bool, the integer and float types,
+// time.Time and []string (see the GetFeature* functions for the formats).
+//
+// A missing custom license field gets the "default=" value if there is one
+// (it must be the last option since it runs to the end of the tag). If the
+// field is "required" and missing, or if any value can't be parsed, the
+// error is a FeatureErrors listing every bad field. The other fields are
+// still filled.
+func (ta *TurboActivate) DecodeFeatures(v interface{}) error {
+	rv := reflect.ValueOf(v)
+
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return errors.New("DecodeFeatures requires a non-nil pointer to a struct")
+	}
+
+	rv = rv.Elem()
+	rt := rv.Type()
+
+	var errs FeatureErrors
+
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+		tag, ok := sf.Tag.Lookup("ta")
+
+		if !ok || tag == "-" || sf.PkgPath != "" {
+			continue
+		}
+
+		name, required, def, hasDefault := parseFeatureTag(tag)
+
+		if name == "" {
+			name = sf.Name
+		}
+
+		value, err := ta.GetFeatureValue(name)
+
+		if err != nil {
+			// TA_FAIL means the feature doesn't exist
+			if !errors.Is(err, ErrFail) {
+				errs = append(errs, &FeatureError{Feature: name, Field: sf.Name, Err: err})
+				continue
+			}
+
+			if hasDefault {
+				value = def
+			} else {
+				if required {
+					errs = append(errs, &FeatureError{Feature: name, Field: sf.Name, Err: ErrFeatureMissing})
+				}
+
+				continue
+			}
+		}
+
+		if err = setFeatureField(rv.Field(i), value); err != nil {
+			errs = append(errs, &FeatureError{Feature: name, Field: sf.Name, Value: value, Err: err})
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+// parseFeatureTag parses a `ta:"name,required,default=value"` tag.
+func parseFeatureTag(tag string) (name string, required bool, def string, hasDefault bool) {
+	var parts = strings.Split(tag, ",")
+
+	name = strings.TrimSpace(parts[0])
+
+	for i := 1; i < len(parts); i++ {
+		opt := strings.TrimSpace(parts[i])
+
+		if opt == "required" {
+			required = true
+		} else if strings.HasPrefix(opt, "default=") {
+			// the default runs to the end of the tag (so it can contain commas)
+			def = strings.TrimPrefix(strings.TrimLeft(strings.Join(parts[i:], ","), " "), "default=")
+			hasDefault = true
+			break
+		}
+	}
+
+	return
+}
+
+// setFeatureField parses value into the struct field f.
+func setFeatureField(f reflect.Value, value string) error {
+	if f.Type() == timeType {
+		t, err := parseFeatureTime(value)
+
+		if err != nil {
+			return err
+		}
+
+		f.Set(reflect.ValueOf(t))
+		return nil
+	}
+
+	switch f.Kind() {
+	case reflect.String:
+		f.SetString(value)
+
+	case reflect.Bool:
+		b, err := parseFeatureBool(value)
+
+		if err != nil {
+			return err
+		}
+
+		f.SetBool(b)
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := parseFeatureInt(value)
+
+		if err != nil {
+			return err
+		}
+
+		if f.OverflowInt(i) {
+			return errors.New("value out of range")
+		}
+
+		f.SetInt(i)
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := parseFeatureUint(value)
+
+		if err != nil {
+			return err
+		}
+
+		if f.OverflowUint(u) {
+			return errors.New("value out of range")
+		}
+
+		f.SetUint(u)
+
+	case reflect.Float32, reflect.Float64:
+		fl, err := parseFeatureFloat(value)
+
+		if err != nil {
+			return err
+		}
+
+		f.SetFloat(fl)
+
+	case reflect.Slice:
+		if f.Type().Elem().Kind() != reflect.String {
+			return errors.New("unsupported field type " + f.Type().String())
+		}
+
+		// build the slice element by element, so named string types
+		// (e.g. []Mod where type Mod string) work too
+		var strs = parseFeatureStrings(value)
+		var slice = reflect.MakeSlice(f.Type(), len(strs), len(strs))
+
+		for i, str := range strs {
+			slice.Index(i).SetString(str)
+		}
+
+		f.Set(slice)
+
+	default:
+		return errors.New("unsupported field type " + f.Type().String())
+	}
+
+	return nil
+}
@@ -0,0 +1,169 @@
+// Copyright 2018 wyDay, LLC. All rights reserved.
+
+package turboactivate_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"golang.wyday.com/turboactivate"
+	"golang.wyday.com/turboactivate/fake"
+	"golang.wyday.com/turboactivate/internal/tatest"
+)
+
+type Mod string
+
+func TestDecodeFeatures(t *testing.T) {
+	ta := tatest.New(t, fake.New(), tatest.WithKey(map[string]string{
+		"seats":          " 5 ",
+		"pro":            "yes",
+		"ratio":          "0.5",
+		"update_expires": "2030-01-31 12:00:00",
+		"modules":        "a, b,,c",
+		"mods":           "x,y",
+		"name":           "Acme",
+		"small":          "300",
+	}))
+
+	var v struct {
+		Seats   int       `ta:"seats"`
+		Pro     bool      `ta:"pro"`
+		Ratio   float64   `ta:"ratio"`
+		Updates time.Time `ta:"update_expires,required"`
+		Modules []string  `ta:"modules"`
+		Mods    []Mod     `ta:"mods"`
+		Name    string    `ta:"name"`
+		Users   uint      `ta:"users,default=1"`
+		Tags    []string  `ta:"tags,default=a, b"`
+		Ignored string    `ta:"-"`
+		Missing string    `ta:"missing"`
+	}
+
+	if err := ta.DecodeFeatures(&v); err != nil {
+		t.Fatal(err)
+	}
+
+	if v.Seats != 5 || !v.Pro || v.Ratio != 0.5 || v.Name != "Acme" || v.Users != 1 {
+		t.Errorf("decoded %+v", v)
+	}
+
+	if want := time.Date(2030, 1, 31, 12, 0, 0, 0, time.UTC); !v.Updates.Equal(want) {
+		t.Errorf("Updates = %v; want %v", v.Updates, want)
+	}
+
+	if want := []string{"a", "b", "c"}; !reflect.DeepEqual(v.Modules, want) {
+		t.Errorf("Modules = %q; want %q", v.Modules, want)
+	}
+
+	if want := []Mod{"x", "y"}; !reflect.DeepEqual(v.Mods, want) {
+		t.Errorf("Mods = %q; want %q", v.Mods, want)
+	}
+
+	if want := []string{"a", "b"}; !reflect.DeepEqual(v.Tags, want) {
+		t.Errorf("Tags = %q; want %q", v.Tags, want)
+	}
+}
+
+func TestDecodeFeaturesErrors(t *testing.T) {
+	ta := tatest.New(t, fake.New(), tatest.WithKey(map[string]string{
+		"seats": "many",
+		"small": "300",
+		"pro":   "maybe",
+		"name":  "Acme",
+	}))
+
+	var v struct {
+		Seats   int            `ta:"seats"`
+		Small   int8           `ta:"small"`
+		Pro     bool           `ta:"pro"`
+		Expires time.Time      `ta:"expires,required"`
+		Bad     map[string]int `ta:"name"`
+		Name    string         `ta:"name"`
+	}
+
+	err := ta.DecodeFeatures(&v)
+
+	var errs turboactivate.FeatureErrors
+
+	if !errors.As(err, &errs) {
+		t.Fatalf("DecodeFeatures() = %v; want FeatureErrors", err)
+	}
+
+	var fields = map[string]*turboactivate.FeatureError{}
+
+	for _, fe := range errs {
+		fields[fe.Field] = fe
+	}
+
+	for _, field := range []string{"Seats", "Small", "Pro", "Expires", "Bad"} {
+		if fields[field] == nil {
+			t.Errorf("no error for %s", field)
+		}
+	}
+
+	if fe := fields["Expires"]; fe != nil && !errors.Is(fe.Err, turboactivate.ErrFeatureMissing) {
+		t.Errorf("Expires error = %v; want ErrFeatureMissing", fe.Err)
+	}
+
+	// the other fields are still filled
+	if v.Name != "Acme" {
+		t.Errorf("Name = %q; want \"Acme\"", v.Name)
+	}
+
+	if err = ta.DecodeFeatures(v); err == nil {
+		t.Error("DecodeFeatures(struct) didn't fail")
+	}
+}
+
+func TestGetFeatureTypes(t *testing.T) {
+	b := fake.New()
+
+	var clock = fake.NewManualClock(time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC))
+	b.SetClock(clock.Now)
+
+	ta := tatest.New(t, b, tatest.WithKey(map[string]string{
+		"seats":          "12",
+		"pro":            "off",
+		"modules":        "a,b",
+		"update_expires": "2025-07-01",
+		"bad_date":       "July",
+	}))
+
+	if i, err := ta.GetFeatureInt("seats"); i != 12 || err != nil {
+		t.Errorf("GetFeatureInt() = %d, %v", i, err)
+	}
+
+	if _, err := ta.GetFeatureInt("modules"); err == nil {
+		t.Error("GetFeatureInt(\"modules\") didn't fail")
+	}
+
+	if v, err := ta.GetFeatureBool("pro"); v || err != nil {
+		t.Errorf("GetFeatureBool() = %v, %v", v, err)
+	}
+
+	if v, err := ta.GetFeatureStrings("modules"); !reflect.DeepEqual(v, []string{"a", "b"}) || err != nil {
+		t.Errorf("GetFeatureStrings() = %q, %v", v, err)
+	}
+
+	var fe *turboactivate.FeatureError
+
+	if _, err := ta.GetFeatureTime("bad_date"); !errors.As(err, &fe) || fe.Value != "July" {
+		t.Errorf("GetFeatureTime(\"bad_date\") = %v; want a FeatureError", err)
+	}
+
+	if _, err := ta.GetFeatureInt("nope"); !errors.Is(err, turboactivate.ErrFail) {
+		t.Errorf("GetFeatureInt(\"nope\") = %v; want ErrFail", err)
+	}
+
+	if ok, err := ta.FeatureNotExpired("update_expires"); !ok || err != nil {
+		t.Errorf("FeatureNotExpired() = %v, %v; want true", ok, err)
+	}
+
+	clock.Advance(31 * 24 * time.Hour)
+
+	if ok, err := ta.FeatureNotExpired("update_expires"); ok || err != nil {
+		t.Errorf("FeatureNotExpired() after the date = %v, %v; want false", ok, err)
+	}
+}
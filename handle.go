@@ -0,0 +1,443 @@
+// Copyright 2018 wyDay, LLC. All rights reserved.
+
+package turboactivate // import "golang.wyday.com/turboactivate"
+
+import (
+	"log/slog"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// ClosedError is returned by the functions of a TurboActivate instance
+// after Close() was called on it.
+type ClosedError struct {
+	// Func is the name of the TurboActivate library function that wasn't called.
+	Func string
+}
+
+func (e *ClosedError) Error() string {
+	if e.Func == "" {
+		return "The TurboActivate instance is closed"
+	}
+
+	return "The TurboActivate instance is closed (" + e.Func + " wasn't called)"
+}
+
+// Is reports whether target is a *ClosedError, so errors.Is(err, ErrClosed)
+// matches the errors of every function.
+func (e *ClosedError) Is(target error) bool {
+	_, ok := target.(*ClosedError)
+	return ok
+}
+
+// ErrClosed matches (with errors.Is) the errors returned after Close().
+var ErrClosed error = &ClosedError{}
+
+// hrClosed is returned by closableBackend after Close(). It's never returned by
+// the TurboActivate library, and taHresultToErr converts it to a *ClosedError.
+const hrClosed HRESULT = 0xFFFFFFFF
+
+// handleRefs counts the open TurboActivate instances of each handle (that is,
+// of each VersionGUID) of each backend, and openStates has their states, so
+// Cleanup() can close them. The handle's trial callback is removed when the
+// last one is closed. The backend's generation in handleGens changes when
+// Cleanup() invalidates its handles, so the instances created before it don't
+// release the handles created after it.
+var (
+	handleRefsMu sync.Mutex
+	handleRefs   = map[handleKey]int{}
+	handleGens   = map[Backend]uint64{}
+	openStates   = map[*handleState]bool{}
+)
+
+// acquireHandle adds the instance's reference to its handle and returns the
+// current generation of its backend.
+func acquireHandle(s *handleState) uint64 {
+	handleRefsMu.Lock()
+	defer handleRefsMu.Unlock()
+
+	handleRefs[s.key]++
+	openStates[s] = true
+	return handleGens[s.key.backend]
+}
+
+// releaseHandle removes the instance's reference to its handle and reports
+// whether it was the last one.
+func releaseHandle(s *handleState) bool {
+	handleRefsMu.Lock()
+	defer handleRefsMu.Unlock()
+
+	var key = s.key
+
+	delete(openStates, s)
+
+	if s.gen != handleGens[key.backend] || handleRefs[key] == 0 {
+		return false
+	}
+
+	handleRefs[key]--
+
+	if handleRefs[key] > 0 {
+		return false
+	}
+
+	delete(handleRefs, key)
+	return true
+}
+
+// resetHandles closes the open instances of the backend and forgets the
+// references to every handle of the backend (see Cleanup).
+func resetHandles(backend Backend) {
+	var id = backendOf(backend)
+
+	handleRefsMu.Lock()
+	defer handleRefsMu.Unlock()
+
+	for s := range openStates {
+		if s.key.backend == id {
+			s.closed.Store(true)
+			delete(openStates, s)
+		}
+	}
+
+	for key := range handleRefs {
+		if key.backend == id {
+			delete(handleRefs, key)
+			forgetHandleLock(key)
+		}
+	}
+
+	handleGens[id]++
+}
+
+// handleState is the part of a TurboActivate instance that Close() and the
+// finalizer share. It must not point back to the TurboActivate instance,
+// otherwise the finalizer never runs.
+type handleState struct {
+	handle      uint32
+	key         handleKey
+	versionGUID string
+	backend     Backend
+	closed      atomic.Bool
+	gen         uint64
+	logger      *slog.Logger
+}
+
+// release removes the reference to the handle, and the trial callback and
+// the handle's mutex if it was the last reference.
+func (s *handleState) release() {
+	if !releaseHandle(s) {
+		return
+	}
+
+	trialCallbacksMu.Lock()
+	_, ok := trialCallbacks[s.key]
+	delete(trialCallbacks, s.key)
+	trialCallbacksMu.Unlock()
+
+	if ok {
+		s.backend.SetTrialCallback(s.handle, nil)
+	}
+
+	forgetHandleLock(s.key)
+}
+
+// leaked is the finalizer of the TurboActivate instances that were never closed.
+func leaked(ta *TurboActivate) {
+	var s = ta.state
+
+	// closed by Cleanup()
+	if s.closed.Load() {
+		return
+	}
+
+	var logger = s.logger
+
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	logger.Warn("TurboActivate instance was garbage collected without calling Close()",
+		slog.String("version_guid", s.versionGUID),
+		slog.Uint64("handle", uint64(s.handle)))
+
+	s.closed.Store(true)
+
+	// removing the trial callback waits for the handle's running call (which
+	// can take a while, e.g. a genuine check), so don't block the finalizers
+	go s.release()
+}
+
+// track registers the instance's reference to its handle and sets the
+// finalizer that warns when the instance is leaked.
+func (ta *TurboActivate) track() {
+	ta.state.gen = acquireHandle(ta.state)
+	runtime.SetFinalizer(ta, leaked)
+}
+
+// Close releases the instance's reference to the handle of its VersionGUID.
+// The functions called afterwards return an error matching ErrClosed, and so
+// does calling Close() again.
+//
+// The other instances for the same VersionGUID aren't affected: the handle's
+// trial callback (see SetTrialCallback) is only removed when the last one is
+// closed. Close waits for a call abandoned by a Context function to finish.
+func (ta *TurboActivate) Close() error {
+
+	if !ta.state.closed.CompareAndSwap(false, true) {
+		return &ClosedError{Func: "Close"}
+	}
+
+	runtime.SetFinalizer(ta, nil)
+
+	// wait for the abandoned call (if any)
+	ta.inflight <- struct{}{}
+	<-ta.inflight
+
+	ta.state.release()
+	return nil
+}
+
+// Closed reports whether Close() was called.
+func (ta *TurboActivate) Closed() bool {
+	return ta.state.closed.Load()
+}
+
+// closableBackend fails every call with hrClosed after the instance is closed.
+type closableBackend struct {
+	b      Backend
+	closed *atomic.Bool
+}
+
+func (c closableBackend) PDetsFromPath(filename string) HRESULT {
+	if c.closed.Load() {
+		return hrClosed
+	}
+
+	return c.b.PDetsFromPath(filename)
+}
+
+func (c closableBackend) PDetsFromByteArray(data []byte) HRESULT {
+	if c.closed.Load() {
+		return hrClosed
+	}
+
+	return c.b.PDetsFromByteArray(data)
+}
+
+func (c closableBackend) GetHandle(versionGUID string) uint32 {
+	if c.closed.Load() {
+		return 0
+	}
+
+	return c.b.GetHandle(versionGUID)
+}
+
+func (c closableBackend) GetVersion() (uint32, uint32, uint32, uint32, HRESULT) {
+	if c.closed.Load() {
+		return 0, 0, 0, 0, hrClosed
+	}
+
+	return c.b.GetVersion()
+}
+
+func (c closableBackend) Cleanup() HRESULT {
+	if c.closed.Load() {
+		return hrClosed
+	}
+
+	return c.b.Cleanup()
+}
+
+func (c closableBackend) Activate(handle uint32, extraData string) HRESULT {
+	if c.closed.Load() {
+		return hrClosed
+	}
+
+	return c.b.Activate(handle, extraData)
+}
+
+func (c closableBackend) ActivationRequestToFile(handle uint32, filename string, extraData string) HRESULT {
+	if c.closed.Load() {
+		return hrClosed
+	}
+
+	return c.b.ActivationRequestToFile(handle, filename, extraData)
+}
+
+func (c closableBackend) ActivateFromFile(handle uint32, filename string) HRESULT {
+	if c.closed.Load() {
+		return hrClosed
+	}
+
+	return c.b.ActivateFromFile(handle, filename)
+}
+
+func (c closableBackend) CheckAndSavePKey(handle uint32, productKey string, flags TAFlags) HRESULT {
+	if c.closed.Load() {
+		return hrClosed
+	}
+
+	return c.b.CheckAndSavePKey(handle, productKey, flags)
+}
+
+func (c closableBackend) Deactivate(handle uint32, eraseProductKey bool) HRESULT {
+	if c.closed.Load() {
+		return hrClosed
+	}
+
+	return c.b.Deactivate(handle, eraseProductKey)
+}
+
+func (c closableBackend) DeactivationRequestToFile(handle uint32, filename string, eraseProductKey bool) HRESULT {
+	if c.closed.Load() {
+		return hrClosed
+	}
+
+	return c.b.DeactivationRequestToFile(handle, filename, eraseProductKey)
+}
+
+func (c closableBackend) GetExtraData(handle uint32) (string, HRESULT) {
+	if c.closed.Load() {
+		return "", hrClosed
+	}
+
+	return c.b.GetExtraData(handle)
+}
+
+func (c closableBackend) GetFeatureValue(handle uint32, featureName string) (string, HRESULT) {
+	if c.closed.Load() {
+		return "", hrClosed
+	}
+
+	return c.b.GetFeatureValue(handle, featureName)
+}
+
+func (c closableBackend) GetPKey(handle uint32) (string, HRESULT) {
+	if c.closed.Load() {
+		return "", hrClosed
+	}
+
+	return c.b.GetPKey(handle)
+}
+
+func (c closableBackend) IsActivated(handle uint32) HRESULT {
+	if c.closed.Load() {
+		return hrClosed
+	}
+
+	return c.b.IsActivated(handle)
+}
+
+func (c closableBackend) IsDateValid(handle uint32, dateTime string, flags TADateCheckFlags) HRESULT {
+	if c.closed.Load() {
+		return hrClosed
+	}
+
+	return c.b.IsDateValid(handle, dateTime, flags)
+}
+
+func (c closableBackend) IsGenuine(handle uint32) HRESULT {
+	if c.closed.Load() {
+		return hrClosed
+	}
+
+	return c.b.IsGenuine(handle)
+}
+
+func (c closableBackend) IsGenuineEx(handle uint32, daysBetweenChecks uint32, graceDaysOnInetErr uint32, skipOffline bool, offlineShowInetErr bool) HRESULT {
+	if c.closed.Load() {
+		return hrClosed
+	}
+
+	return c.b.IsGenuineEx(handle, daysBetweenChecks, graceDaysOnInetErr, skipOffline, offlineShowInetErr)
+}
+
+func (c closableBackend) GenuineDays(handle uint32, daysBetweenChecks uint32, graceDaysOnInetErr uint32) (uint32, bool, HRESULT) {
+	if c.closed.Load() {
+		return 0, false, hrClosed
+	}
+
+	return c.b.GenuineDays(handle, daysBetweenChecks, graceDaysOnInetErr)
+}
+
+func (c closableBackend) IsProductKeyValid(handle uint32) HRESULT {
+	if c.closed.Load() {
+		return hrClosed
+	}
+
+	return c.b.IsProductKeyValid(handle)
+}
+
+func (c closableBackend) SetCustomProxy(proxy string) HRESULT {
+	if c.closed.Load() {
+		return hrClosed
+	}
+
+	return c.b.SetCustomProxy(proxy)
+}
+
+func (c closableBackend) TrialDaysRemaining(handle uint32, flags TAFlags) (uint32, HRESULT) {
+	if c.closed.Load() {
+		return 0, hrClosed
+	}
+
+	return c.b.TrialDaysRemaining(handle, flags)
+}
+
+func (c closableBackend) UseTrial(handle uint32, flags TAFlags, extraData string) HRESULT {
+	if c.closed.Load() {
+		return hrClosed
+	}
+
+	return c.b.UseTrial(handle, flags, extraData)
+}
+
+func (c closableBackend) UseTrialVerifiedRequest(handle uint32, filename string, extraData string) HRESULT {
+	if c.closed.Load() {
+		return hrClosed
+	}
+
+	return c.b.UseTrialVerifiedRequest(handle, filename, extraData)
+}
+
+func (c closableBackend) UseTrialVerifiedFromFile(handle uint32, filename string, flags TAFlags) HRESULT {
+	if c.closed.Load() {
+		return hrClosed
+	}
+
+	return c.b.UseTrialVerifiedFromFile(handle, filename, flags)
+}
+
+func (c closableBackend) ExtendTrial(handle uint32, flags TAFlags, trialExtension string) HRESULT {
+	if c.closed.Load() {
+		return hrClosed
+	}
+
+	return c.b.ExtendTrial(handle, flags, trialExtension)
+}
+
+func (c closableBackend) SetCustomActDataPath(handle uint32, directory string) HRESULT {
+	if c.closed.Load() {
+		return hrClosed
+	}
+
+	return c.b.SetCustomActDataPath(handle, directory)
+}
+
+func (c closableBackend) SetTrialCallback(handle uint32, callback func(status uint32)) HRESULT {
+	if c.closed.Load() {
+		return hrClosed
+	}
+
+	return c.b.SetTrialCallback(handle, callback)
+}
+
+func (c closableBackend) BlackListKeys(handle uint32, keys []string) HRESULT {
+	if c.closed.Load() {
+		return hrClosed
+	}
+
+	return c.b.BlackListKeys(handle, keys)
+}
@@ -0,0 +1,185 @@
+// Copyright 2018 wyDay, LLC. All rights reserved.
+
+package turboactivate_test
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.wyday.com/turboactivate"
+	"golang.wyday.com/turboactivate/fake"
+)
+
+func TestClose(t *testing.T) {
+	b := fake.New()
+
+	ta, err := turboactivate.New(testGUID, turboactivate.WithBackend(b))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ta.Closed() {
+		t.Error("Closed() = true before Close()")
+	}
+
+	if err = ta.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if !ta.Closed() {
+		t.Error("Closed() = false after Close()")
+	}
+
+	if err = ta.Close(); !errors.Is(err, turboactivate.ErrClosed) {
+		t.Errorf("second Close() = %v; want ErrClosed", err)
+	}
+
+	var calls = b.Calls("IsActivated")
+
+	if _, err = ta.IsActivated(); !errors.Is(err, turboactivate.ErrClosed) {
+		t.Errorf("IsActivated() = %v; want ErrClosed", err)
+	}
+
+	if b.Calls("IsActivated") != calls {
+		t.Error("IsActivated was called on the backend after Close()")
+	}
+
+	if err = ta.SetTrialCallback(func(turboactivate.TrialEvent) {}); !errors.Is(err, turboactivate.ErrClosed) {
+		t.Errorf("SetTrialCallback() = %v; want ErrClosed", err)
+	}
+}
+
+func TestCloseKeepsCallbackOfOtherInstances(t *testing.T) {
+	b := fake.New()
+
+	ta1, err := turboactivate.New(testGUID, turboactivate.WithBackend(b))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ta2, err := turboactivate.New(testGUID, turboactivate.WithBackend(b))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var events = make(chan turboactivate.TrialEvent, 4)
+
+	if err = ta1.SetTrialCallback(func(ev turboactivate.TrialEvent) { events <- ev }); err != nil {
+		t.Fatal(err)
+	}
+
+	// ta2 still references the handle
+	ta1.Close()
+
+	b.FireTrialCallback(testGUID, 0x00)
+	waitEvent(t, events)
+
+	// the last reference removes the callback
+	ta2.Close()
+
+	b.FireTrialCallback(testGUID, 0x00)
+	noEvent(t, events)
+}
+
+func TestCloseDoesntReleaseOtherBackends(t *testing.T) {
+	b1 := fake.New()
+	b2 := fake.New()
+
+	ta1, err := turboactivate.New(testGUID, turboactivate.WithBackend(b1))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ta2, err := turboactivate.New(testGUID2, turboactivate.WithBackend(b2))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer ta2.Close()
+
+	var events1 = make(chan turboactivate.TrialEvent, 4)
+	var events2 = make(chan turboactivate.TrialEvent, 4)
+
+	if err = ta1.SetTrialCallback(func(ev turboactivate.TrialEvent) { events1 <- ev }); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = ta2.SetTrialCallback(func(ev turboactivate.TrialEvent) { events2 <- ev }); err != nil {
+		t.Fatal(err)
+	}
+
+	// both fake backends hand out handle 1, but closing ta1 must only
+	// release b1's handle (and it's b1's last reference)
+	ta1.Close()
+
+	b1.FireTrialCallback(testGUID, 0x00)
+	noEvent(t, events1)
+
+	b2.FireTrialCallback(testGUID2, 0x00)
+	waitEvent(t, events2)
+}
+
+// syncBuffer is a bytes.Buffer that's safe for concurrent use.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (s *syncBuffer) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.buf.Write(p)
+}
+
+func (s *syncBuffer) String() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.buf.String()
+}
+
+func TestLeakedInstanceIsReleased(t *testing.T) {
+	b := fake.New()
+
+	var logs syncBuffer
+	var logger = slog.New(slog.NewTextHandler(&logs, nil))
+
+	func() {
+		ta, err := turboactivate.New(testGUID, turboactivate.WithBackend(b), turboactivate.WithLogger(logger))
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ta.SetTrialCallback(func(turboactivate.TrialEvent) {})
+	}()
+
+	var calls = b.Calls("SetTrialCallback")
+	var deadline = time.Now().Add(5 * time.Second)
+
+	// the finalizer warns, then removes the trial callback on another goroutine
+	for b.Calls("SetTrialCallback") == calls {
+		if time.Now().After(deadline) {
+			t.Fatal("the leaked instance wasn't released")
+		}
+
+		runtime.GC()
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if !strings.Contains(logs.String(), "without calling Close()") {
+		t.Errorf("no warning was logged: %q", logs.String())
+	}
+}
@@ -0,0 +1,106 @@
+// Copyright 2018 wyDay, LLC. All rights reserved.
+
+// Package tatest creates the TurboActivate instances the tests of this
+// module run against, on a fake backend.
+package tatest // import "golang.wyday.com/turboactivate/internal/tatest"
+
+import (
+	"testing"
+
+	"golang.wyday.com/turboactivate"
+	"golang.wyday.com/turboactivate/fake"
+)
+
+const (
+	// GUID is the VersionGUID of the product the instances are for.
+	GUID = "18324776654b3946fc44a5f3.49025204"
+
+	// GUID2 is the VersionGUID of a second product.
+	GUID2 = "28324776654b3946fc44a5f3.49025205"
+
+	// PKey is the product key WithKey adds and saves.
+	PKey = "AAAA-BBBB-CCCC-DDDD-EEEE-FFFF-GGGG"
+)
+
+type config struct {
+	guid      string
+	key       *fake.Key
+	activate  bool
+	extraData string
+	options   []turboactivate.Option
+}
+
+// Option configures the instance returned by New.
+type Option func(*config)
+
+// WithGUID makes the instance for the VersionGUID instead of GUID.
+func WithGUID(guid string) Option {
+	return func(c *config) {
+		c.guid = guid
+	}
+}
+
+// WithKey adds PKey with the features to the backend and saves it on the
+// instance with CheckAndSavePKey.
+func WithKey(features map[string]string) Option {
+	return func(c *config) {
+		c.key = &fake.Key{Features: features}
+	}
+}
+
+// WithActivation saves PKey (see WithKey) and activates it with the extra data.
+func WithActivation(extraData string) Option {
+	return func(c *config) {
+		if c.key == nil {
+			c.key = &fake.Key{}
+		}
+
+		c.activate = true
+		c.extraData = extraData
+	}
+}
+
+// WithOptions passes the options on to turboactivate.New, after WithBackend.
+func WithOptions(opts ...turboactivate.Option) Option {
+	return func(c *config) {
+		c.options = append(c.options, opts...)
+	}
+}
+
+// New returns an instance on b, which is closed when the test ends. It
+// stops the test if the instance can't be created or set up.
+func New(t testing.TB, b *fake.Backend, opts ...Option) *turboactivate.TurboActivate {
+	t.Helper()
+
+	var c = config{guid: GUID}
+
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	if c.key != nil {
+		b.AddKey(c.guid, PKey, *c.key)
+	}
+
+	ta, err := turboactivate.New(c.guid, append([]turboactivate.Option{turboactivate.WithBackend(b)}, c.options...)...)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { ta.Close() })
+
+	if c.key != nil {
+		if ok, err := ta.CheckAndSavePKey(PKey, turboactivate.TAUser); !ok || err != nil {
+			t.Fatalf("CheckAndSavePKey() = %v, %v", ok, err)
+		}
+	}
+
+	if c.activate {
+		if err := ta.Activate(c.extraData); err != nil {
+			t.Fatalf("Activate() = %v", err)
+		}
+	}
+
+	return ta
+}
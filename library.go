@@ -0,0 +1,86 @@
+// Copyright 2018 wyDay, LLC. All rights reserved.
+
+package turboactivate // import "golang.wyday.com/turboactivate"
+
+import "strconv"
+
+func versionString(major uint32, minor uint32, build uint32, revision uint32) string {
+	return strconv.FormatUint(uint64(major), 10) + "." +
+		strconv.FormatUint(uint64(minor), 10) + "." +
+		strconv.FormatUint(uint64(build), 10) + "." +
+		strconv.FormatUint(uint64(revision), 10)
+}
+
+// Version gets the version of the native TurboActivate library
+// (e.g. 4, 4, 4, 0 for "4.4.4.0"). Log it to make troubleshooting easier.
+func Version() (major uint32, minor uint32, build uint32, revision uint32, err error) {
+
+	if defaultBackend == nil {
+		return 0, 0, 0, 0, defaultBackendErr
+	}
+
+	return versionOf(serialize(defaultBackend))
+}
+
+// Version gets the version of the TurboActivate library this instance uses.
+func (ta *TurboActivate) Version() (major uint32, minor uint32, build uint32, revision uint32, err error) {
+	return versionOf(ta.backend)
+}
+
+func versionOf(backend Backend) (uint32, uint32, uint32, uint32, error) {
+
+	major, minor, build, revision, ret := backend.GetVersion()
+
+	// TA_OK
+	if ret == 0x00 {
+		return major, minor, build, revision, nil
+	}
+
+	return 0, 0, 0, 0, taHresultToErr(ret, "GetVersion")
+}
+
+// Cleanup releases the resources used by the native TurboActivate library.
+// Call it once when your app shuts down, after the last TurboActivate
+// function has returned. It waits for the running calls to finish.
+//
+// Cleanup invalidates the handles of every TurboActivate instance and closes
+// them, so their functions (and Close) return an error matching ErrClosed
+// afterwards. Create new instances instead.
+func Cleanup() error {
+
+	if defaultBackend == nil {
+		return defaultBackendErr
+	}
+
+	return cleanupBackend(serialize(defaultBackend))
+}
+
+func cleanupBackend(backend Backend) error {
+
+	var ret = backend.Cleanup()
+
+	resetTrialCallbacks(backend)
+
+	resetHandles(backend)
+
+	// TA_OK
+	if ret == 0x00 {
+		return nil
+	}
+
+	return taHresultToErr(ret, "Cleanup")
+}
+
+// BlackListKeys blacklists the product keys (e.g. leaked keys) so they're
+// no longer valid on this computer, without contacting the LimeLM servers.
+func (ta *TurboActivate) BlackListKeys(keys []string) error {
+
+	var ret = ta.backend.BlackListKeys(ta.handle, keys)
+
+	// TA_OK
+	if ret == 0x00 {
+		return nil
+	}
+
+	return taHresultToErr(ret, "BlackListKeys")
+}
@@ -0,0 +1,76 @@
+// Copyright 2018 wyDay, LLC. All rights reserved.
+
+package turboactivate_test
+
+import (
+	"errors"
+	"testing"
+
+	"golang.wyday.com/turboactivate"
+	"golang.wyday.com/turboactivate/fake"
+)
+
+func TestVersion(t *testing.T) {
+	b := fake.New()
+	b.SetVersion(4, 5, 6, 7)
+
+	ta, err := turboactivate.New(testGUID, turboactivate.WithBackend(b))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer ta.Close()
+
+	if major, minor, build, revision, err := ta.Version(); major != 4 || minor != 5 || build != 6 || revision != 7 || err != nil {
+		t.Errorf("Version() = %d.%d.%d.%d, %v; want 4.5.6.7", major, minor, build, revision, err)
+	}
+
+	b.FailNext("GetVersion", turboactivate.TAFail)
+
+	var taErr *turboactivate.TAError
+
+	if _, _, _, _, err := ta.Version(); !errors.As(err, &taErr) || taErr.Func != "GetVersion" {
+		t.Errorf("Version() of a failing library = %v", err)
+	}
+}
+
+func TestBlackListKeys(t *testing.T) {
+	b := fake.New()
+	b.AddKey(testGUID, testPKey, fake.Key{})
+
+	ta, err := turboactivate.New(testGUID, turboactivate.WithBackend(b))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer ta.Close()
+
+	if err = ta.BlackListKeys([]string{testPKey}); err != nil {
+		t.Fatal(err)
+	}
+
+	if ok, err := ta.CheckAndSavePKey(testPKey, turboactivate.TAUser); ok || err != nil {
+		t.Errorf("CheckAndSavePKey() of a blacklisted key = %v, %v; want false", ok, err)
+	}
+
+	if err = ta.BlackListKeys(nil); !errors.Is(err, turboactivate.ErrInvalidArgs) {
+		t.Errorf("BlackListKeys(nil) = %v; want ErrInvalidArgs", err)
+	}
+}
+
+func TestLibraryWithoutLibrary(t *testing.T) {
+
+	if turboactivate.DefaultBackend() != nil {
+		t.Skip("built with the TurboActivate library")
+	}
+
+	if _, _, _, _, err := turboactivate.Version(); err == nil {
+		t.Error("Version() without the library succeeded")
+	}
+
+	if err := turboactivate.Cleanup(); err == nil {
+		t.Error("Cleanup() without the library succeeded")
+	}
+}
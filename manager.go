@@ -0,0 +1,472 @@
+// Copyright 2018 wyDay, LLC. All rights reserved.
+
+package turboactivate // import "golang.wyday.com/turboactivate"
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrUnknownProduct is returned by the Manager functions when no product
+	// is registered with the name (or VersionGUID).
+	ErrUnknownProduct = errors.New("No product is registered with that name or VersionGUID")
+
+	// ErrProductExists is returned by Manager.Register when a product is
+	// already registered with the name or VersionGUID.
+	ErrProductExists = errors.New("A product is already registered with that name or VersionGUID")
+
+	// ErrManagerClosed is returned by Manager.Register after Manager.Close().
+	ErrManagerClosed = errors.New("The manager is closed")
+)
+
+// Product is a product registered with a Manager.
+type Product struct {
+	// Name identifies the product in the Manager (e.g. "Editor").
+	// Defaults to the VersionGUID.
+	Name string
+
+	// VersionGUID is the VersionGUID of the product in LimeLM.
+	VersionGUID string
+
+	// Options configure the product's TurboActivate instance, after the
+	// options passed to NewManager. Pass the product details here
+	// (e.g. WithProductDetailsFile), since every product has its own.
+	Options []Option
+
+	// Features are the names of the custom license fields read by
+	// StatusAll and Entitlements.
+	Features []string
+}
+
+// ProductError is the error of one product returned by the Manager functions
+// that work on every product.
+type ProductError struct {
+	// Product is the name of the product.
+	Product string
+
+	// Err is the underlying error.
+	Err error
+}
+
+// Error returns the description of the error.
+func (e *ProductError) Error() string {
+	return "product \"" + e.Product + "\": " + e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *ProductError) Unwrap() error {
+	return e.Err
+}
+
+// ProductErrors is the list of errors returned by StatusAll and Entitlements.
+type ProductErrors []*ProductError
+
+// Error returns the description of every error.
+func (e ProductErrors) Error() string {
+	var msgs = make([]string, len(e))
+
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+
+	return strings.Join(msgs, "; ")
+}
+
+// Unwrap returns the errors, so errors.Is and errors.As match any of them.
+func (e ProductErrors) Unwrap() []error {
+	var errs = make([]error, len(e))
+
+	for i, err := range e {
+		errs[i] = err
+	}
+
+	return errs
+}
+
+// Manager manages the licensing of several products (each with its own
+// VersionGUID and TurboActivate.dat) in the same app. For example:
+//
+//	m := turboactivate.NewManager(turboactivate.WithRetryPolicy(turboactivate.DefaultRetryPolicy()))
+//	defer m.Close()
+//
+//	editor, err := m.Register(turboactivate.Product{
+//		Name:        "Editor",
+//		VersionGUID: "18324776654b3946fc44a5f3.49025204",
+//		Options:     []turboactivate.Option{turboactivate.WithProductDetailsFile("Editor.dat")},
+//		Features:    []string{"seats"},
+//	})
+//
+// A Manager is safe for concurrent use by multiple goroutines.
+type Manager struct {
+	opts []Option
+
+	mu       sync.RWMutex
+	products []*managedProduct
+	closed   bool
+}
+
+// managedProduct is a registered product and its TurboActivate instance.
+type managedProduct struct {
+	Product
+	ta *TurboActivate
+}
+
+// is reports whether the product has the name (or VersionGUID).
+func (mp *managedProduct) is(name string) bool {
+	return mp.Name == name || mp.VersionGUID == name
+}
+
+// NewManager creates a Manager. The options (e.g. WithBackend, WithLogger,
+// WithRetryPolicy) are used for every product.
+func NewManager(opts ...Option) *Manager {
+	return &Manager{opts: opts}
+}
+
+// Register creates the TurboActivate instance of the product (see New) and
+// returns it. The Manager closes it when the product is unregistered or the
+// Manager is closed, so don't close it yourself.
+//
+// The name and VersionGUID of the product can't be the name or VersionGUID
+// of a product that's already registered.
+func (m *Manager) Register(p Product) (*TurboActivate, error) {
+
+	if p.Name == "" {
+		p.Name = p.VersionGUID
+	}
+
+	if err := m.canRegister(p); err != nil {
+		return nil, err
+	}
+
+	var opts = make([]Option, 0, len(m.opts)+len(p.Options))
+	opts = append(opts, m.opts...)
+	opts = append(opts, p.Options...)
+
+	// New can be slow (e.g. loading the product details), so it's called
+	// without holding the lock
+	ta, err := New(p.VersionGUID, opts...)
+
+	if err != nil {
+		return nil, &ProductError{Product: p.Name, Err: err}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// the product could have been registered (or the Manager closed) meanwhile
+	if err = m.canRegisterLocked(p); err != nil {
+		ta.Close()
+		return nil, err
+	}
+
+	m.products = append(m.products, &managedProduct{Product: p, ta: ta})
+	return ta, nil
+}
+
+// canRegister returns the error of registering p, if any.
+func (m *Manager) canRegister(p Product) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.canRegisterLocked(p)
+}
+
+// canRegisterLocked is canRegister, with m.mu held.
+func (m *Manager) canRegisterLocked(p Product) error {
+
+	if m.closed {
+		return ErrManagerClosed
+	}
+
+	for _, mp := range m.products {
+		if mp.is(p.Name) || mp.is(p.VersionGUID) {
+			return ErrProductExists
+		}
+	}
+
+	return nil
+}
+
+// Unregister removes the product and closes its TurboActivate instance.
+func (m *Manager) Unregister(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, mp := range m.products {
+		if mp.is(name) {
+			m.products = append(m.products[:i:i], m.products[i+1:]...)
+			return ignoreClosed(mp.ta.Close())
+		}
+	}
+
+	return ErrUnknownProduct
+}
+
+// Close closes the TurboActivate instance of every product. The products
+// can't be used, and no products can be registered, afterwards.
+func (m *Manager) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var errs ProductErrors
+
+	for _, mp := range m.products {
+		if err := ignoreClosed(mp.ta.Close()); err != nil {
+			errs = append(errs, &ProductError{Product: mp.Name, Err: err})
+		}
+	}
+
+	m.products = nil
+	m.closed = true
+
+	if errs != nil {
+		return errs
+	}
+
+	return nil
+}
+
+// ignoreClosed ignores the error of closing an instance that was already closed.
+func ignoreClosed(err error) error {
+	if errors.Is(err, ErrClosed) {
+		return nil
+	}
+
+	return err
+}
+
+// product finds the product by name (or VersionGUID).
+func (m *Manager) product(name string) (*managedProduct, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, mp := range m.products {
+		if mp.is(name) {
+			return mp, nil
+		}
+	}
+
+	return nil, ErrUnknownProduct
+}
+
+// snapshot returns the registered products.
+func (m *Manager) snapshot() []*managedProduct {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return append([]*managedProduct(nil), m.products...)
+}
+
+// Product returns the TurboActivate instance of the product with the name
+// (or VersionGUID).
+func (m *Manager) Product(name string) (*TurboActivate, error) {
+	mp, err := m.product(name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return mp.ta, nil
+}
+
+// Products returns the names of the products, in the order they were registered.
+func (m *Manager) Products() []string {
+	var products = m.snapshot()
+	var names = make([]string, len(products))
+
+	for i, mp := range products {
+		names[i] = mp.Name
+	}
+
+	return names
+}
+
+// Feature gets the value of the feature (custom license field) of the
+// product with the name (or VersionGUID). See GetFeatureValue.
+func (m *Manager) Feature(product string, featureName string) (string, error) {
+	mp, err := m.product(product)
+
+	if err != nil {
+		return "", err
+	}
+
+	return mp.ta.GetFeatureValue(featureName)
+}
+
+// StatusAll gets the Status() of every product, by name. The products are
+// checked concurrently, and each one reads its own Product.Features along
+// with opts.Features.
+//
+// If some of the products fail, StatusAll returns the (partial) status of
+// every product along with a ProductErrors.
+func (m *Manager) StatusAll(ctx context.Context, opts StatusOptions) (map[string]LicenseStatus, error) {
+	return statusAll(ctx, opts, m.snapshot())
+}
+
+// statusAll gets the status of the products. See StatusAll.
+func statusAll(ctx context.Context, opts StatusOptions, products []*managedProduct) (map[string]LicenseStatus, error) {
+
+	var statuses = make([]LicenseStatus, len(products))
+	var errs = make([]error, len(products))
+	var wg sync.WaitGroup
+
+	for i, mp := range products {
+		var popts = opts
+
+		if len(mp.Features) != 0 {
+			popts.Features = append(append([]string(nil), opts.Features...), mp.Features...)
+		}
+
+		wg.Add(1)
+
+		go func(i int, mp *managedProduct, popts StatusOptions) {
+			defer wg.Done()
+
+			statuses[i], errs[i] = mp.ta.Status(ctx, popts)
+		}(i, mp, popts)
+	}
+
+	wg.Wait()
+
+	var all = make(map[string]LicenseStatus, len(products))
+	var perrs ProductErrors
+
+	for i, mp := range products {
+		all[mp.Name] = statuses[i]
+
+		if errs[i] != nil {
+			perrs = append(perrs, &ProductError{Product: mp.Name, Err: errs[i]})
+		}
+	}
+
+	if perrs != nil {
+		return all, perrs
+	}
+
+	return all, nil
+}
+
+// Entitlement is what the license (or trial) of one product allows.
+type Entitlement struct {
+	// Product is the name of the product.
+	Product string `json:"product"`
+
+	// VersionGUID is the VersionGUID of the product.
+	VersionGUID string `json:"version_guid"`
+
+	// Licensed is whether the product is activated and genuine (or couldn't
+	// be verified because of an internet error, see IGRInternetError).
+	Licensed bool `json:"licensed"`
+
+	// Trial is whether the product isn't licensed, but its trial has days left.
+	Trial bool `json:"trial"`
+
+	// TrialDaysLeft is the number of days left in the trial.
+	TrialDaysLeft uint32 `json:"trial_days_left,omitempty"`
+
+	// Features are the Product.Features (and StatusOptions.Features) of the
+	// license. They're only set if the product is licensed.
+	Features map[string]string `json:"features,omitempty"`
+
+	// Error is the error getting the status of the product (if any).
+	Error string `json:"error,omitempty"`
+}
+
+// Entitled reports whether the product can be used, that is whether it's
+// licensed or in its trial.
+func (e Entitlement) Entitled() bool {
+	return e.Licensed || e.Trial
+}
+
+// Entitlements is the combined view of the entitlements of every product.
+type Entitlements struct {
+	// Products are the entitlements of the products, in the order they were registered.
+	Products []Entitlement `json:"products"`
+
+	// CheckedAt is when the entitlements were checked.
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// Product returns the entitlement of the product with the name.
+func (e Entitlements) Product(name string) (Entitlement, bool) {
+	for _, ent := range e.Products {
+		if ent.Product == name || ent.VersionGUID == name {
+			return ent, true
+		}
+	}
+
+	return Entitlement{}, false
+}
+
+// Entitled reports whether the product with the name can be used.
+func (e Entitlements) Entitled(name string) bool {
+	ent, ok := e.Product(name)
+	return ok && ent.Entitled()
+}
+
+// Feature returns the value of the feature from the first licensed product
+// (in the order they were registered) that has it, and the name of that product.
+func (e Entitlements) Feature(name string) (value string, product string, ok bool) {
+	for _, ent := range e.Products {
+		if value, ok = ent.Features[name]; ok {
+			return value, ent.Product, true
+		}
+	}
+
+	return "", "", false
+}
+
+// Entitlements gets the StatusAll() of the products and combines them into
+// what each product is entitled to. The products that failed are included
+// (with Error set), along with a ProductErrors.
+func (m *Manager) Entitlements(ctx context.Context, opts StatusOptions) (Entitlements, error) {
+
+	// the statuses are of these products, even if some are registered
+	// (or unregistered) meanwhile
+	var products = m.snapshot()
+
+	all, err := statusAll(ctx, opts, products)
+
+	var perrs ProductErrors
+	errors.As(err, &perrs)
+
+	var ents = Entitlements{
+		Products:  make([]Entitlement, 0, len(products)),
+		CheckedAt: time.Now().UTC(),
+	}
+
+	for _, mp := range products {
+		var st = all[mp.Name]
+
+		var ent = Entitlement{
+			Product:     mp.Name,
+			VersionGUID: mp.VersionGUID,
+		}
+
+		for _, perr := range perrs {
+			if perr.Product == mp.Name {
+				ent.Error = perr.Err.Error()
+			}
+		}
+
+		switch st.GenuineResult {
+		case IGRGenuine, IGRGenuineFeaturesChanged, IGRInternetError:
+			ent.Licensed = st.Activated && ent.Error == ""
+		}
+
+		if ent.Licensed {
+			ent.Features = st.Features
+		} else if st.TrialStarted && st.TrialDaysLeft > 0 && ent.Error == "" {
+			ent.Trial = true
+			ent.TrialDaysLeft = st.TrialDaysLeft
+		}
+
+		ents.Products = append(ents.Products, ent)
+	}
+
+	return ents, err
+}
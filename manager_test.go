@@ -0,0 +1,210 @@
+// Copyright 2018 wyDay, LLC. All rights reserved.
+
+package turboactivate_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"golang.wyday.com/turboactivate"
+	"golang.wyday.com/turboactivate/fake"
+)
+
+// register registers the product on m, failing the test if it can't.
+func register(t *testing.T, m *turboactivate.Manager, p turboactivate.Product) *turboactivate.TurboActivate {
+	t.Helper()
+
+	ta, err := m.Register(p)
+
+	if err != nil {
+		t.Fatalf("Register(%s) = %v", p.Name, err)
+	}
+
+	return ta
+}
+
+func TestManagerRegister(t *testing.T) {
+	var m = turboactivate.NewManager()
+	defer m.Close()
+
+	// every backend returns handle 1, which must not mix the products up
+	var editor = register(t, m, turboactivate.Product{Name: "Editor", VersionGUID: testGUID, Options: []turboactivate.Option{turboactivate.WithBackend(fake.New())}})
+	register(t, m, turboactivate.Product{VersionGUID: testGUID2, Options: []turboactivate.Option{turboactivate.WithBackend(fake.New())}})
+
+	for _, p := range []turboactivate.Product{
+		{Name: "Editor", VersionGUID: "00000000000000000000000.00000000"},
+		{Name: "Other", VersionGUID: testGUID},
+		// the name of one product is the VersionGUID of another
+		{Name: testGUID, VersionGUID: "00000000000000000000000.00000000"},
+		{Name: "Other", VersionGUID: "Editor"},
+	} {
+		p.Options = []turboactivate.Option{turboactivate.WithBackend(fake.New())}
+
+		if _, err := m.Register(p); !errors.Is(err, turboactivate.ErrProductExists) {
+			t.Errorf("Register(%s, %s) = %v; want ErrProductExists", p.Name, p.VersionGUID, err)
+		}
+	}
+
+	if names := fmt.Sprint(m.Products()); names != "[Editor "+testGUID2+"]" {
+		t.Errorf("Products() = %s", names)
+	}
+
+	for _, name := range []string{"Editor", testGUID} {
+		if ta, err := m.Product(name); ta != editor || err != nil {
+			t.Errorf("Product(%s) = %p, %v; want %p", name, ta, err, editor)
+		}
+	}
+
+	if err := m.Unregister(testGUID2); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := m.Product(testGUID2); !errors.Is(err, turboactivate.ErrUnknownProduct) {
+		t.Errorf("Product() after Unregister() = %v; want ErrUnknownProduct", err)
+	}
+
+	if err := m.Unregister(testGUID2); !errors.Is(err, turboactivate.ErrUnknownProduct) {
+		t.Errorf("Unregister() twice = %v; want ErrUnknownProduct", err)
+	}
+
+	if err := m.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := editor.IsActivated(); !errors.Is(err, turboactivate.ErrClosed) {
+		t.Errorf("IsActivated() after Close() = %v; want ErrClosed", err)
+	}
+
+	if _, err := m.Register(turboactivate.Product{VersionGUID: testGUID, Options: []turboactivate.Option{turboactivate.WithBackend(fake.New())}}); !errors.Is(err, turboactivate.ErrManagerClosed) {
+		t.Errorf("Register() after Close() = %v; want ErrManagerClosed", err)
+	}
+}
+
+func TestManagerEntitlements(t *testing.T) {
+	var flags = turboactivate.TAUser | turboactivate.TAVerifiedTrial
+
+	b1 := fake.New()
+	b1.AddKey(testGUID, testPKey, fake.Key{Features: map[string]string{"seats": "5"}})
+
+	b2 := fake.New()
+	b2.AddProduct(testGUID2, fake.Product{TrialDays: 10})
+
+	var m = turboactivate.NewManager()
+	defer m.Close()
+
+	var editor = register(t, m, turboactivate.Product{Name: "Editor", VersionGUID: testGUID, Features: []string{"seats"}, Options: []turboactivate.Option{turboactivate.WithBackend(b1)}})
+	var viewer = register(t, m, turboactivate.Product{Name: "Viewer", VersionGUID: testGUID2, Options: []turboactivate.Option{turboactivate.WithBackend(b2)}})
+
+	editor.CheckAndSavePKey(testPKey, turboactivate.TAUser)
+
+	if err := editor.Activate(""); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := viewer.UseTrial(flags, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if seats, err := m.Feature("Editor", "seats"); seats != "5" || err != nil {
+		t.Errorf("Feature() = %q, %v; want 5", seats, err)
+	}
+
+	var opts = turboactivate.StatusOptions{TrialFlags: flags}
+
+	all, err := m.StatusAll(context.Background(), opts)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !all["Editor"].Activated || all["Editor"].Features["seats"] != "5" || all["Viewer"].TrialDaysLeft != 10 {
+		t.Errorf("StatusAll() = %+v", all)
+	}
+
+	ents, err := m.Entitlements(context.Background(), opts)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ent, _ := ents.Product("Editor"); !ent.Licensed || ent.Trial {
+		t.Errorf("Editor = %+v; want licensed", ent)
+	}
+
+	if ent, _ := ents.Product(testGUID2); ent.Licensed || !ent.Trial || ent.TrialDaysLeft != 10 {
+		t.Errorf("Viewer = %+v; want a trial", ent)
+	}
+
+	if value, product, ok := ents.Feature("seats"); value != "5" || product != "Editor" || !ok {
+		t.Errorf("Feature() = %q, %q, %v", value, product, ok)
+	}
+
+	// a failed product is reported, without hiding the others
+	b2.FailNext("IsProductKeyValid", turboactivate.TAEInvalidFlags)
+
+	ents, err = m.Entitlements(context.Background(), opts)
+
+	var perrs turboactivate.ProductErrors
+
+	if !errors.As(err, &perrs) || len(perrs) != 1 || perrs[0].Product != "Viewer" {
+		t.Fatalf("Entitlements() = %v; want an error for Viewer", err)
+	}
+
+	if !ents.Entitled("Editor") || ents.Entitled("Viewer") {
+		t.Errorf("Entitlements() = %+v", ents)
+	}
+
+	if ent, _ := ents.Product("Viewer"); ent.Error == "" {
+		t.Error("the error of Viewer isn't set")
+	}
+}
+
+func TestManagerConcurrent(t *testing.T) {
+	var m = turboactivate.NewManager()
+	defer m.Close()
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var registered int
+
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+
+		// every goroutine registers the same product, so only one succeeds
+		go func() {
+			defer wg.Done()
+
+			_, err := m.Register(turboactivate.Product{Name: "Editor", VersionGUID: testGUID, Options: []turboactivate.Option{turboactivate.WithBackend(fake.New())}})
+
+			switch {
+			case err == nil:
+				mu.Lock()
+				registered++
+				mu.Unlock()
+
+			case !errors.Is(err, turboactivate.ErrProductExists):
+				t.Errorf("Register() = %v", err)
+			}
+		}()
+
+		go func() {
+			defer wg.Done()
+
+			m.StatusAll(context.Background(), turboactivate.StatusOptions{SkipGenuineCheck: true})
+			m.Entitlements(context.Background(), turboactivate.StatusOptions{SkipGenuineCheck: true})
+		}()
+	}
+
+	wg.Wait()
+
+	if registered != 1 {
+		t.Errorf("the product was registered %d times; want once", registered)
+	}
+
+	if names := m.Products(); len(names) != 1 {
+		t.Errorf("Products() = %v", names)
+	}
+}
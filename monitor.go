@@ -0,0 +1,336 @@
+// Copyright 2018 wyDay, LLC. All rights reserved.
+
+package turboactivate // import "golang.wyday.com/turboactivate"
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// MonitorEventType is the type of a MonitorEvent.
+type MonitorEventType int
+
+const (
+	// EventGenuine means IsGenuineEx() returned IGRGenuine.
+	EventGenuine MonitorEventType = iota
+
+	// EventFeaturesChanged means IsGenuineEx() returned IGRGenuineFeaturesChanged.
+	// Re-read the feature values with GetFeatureValue().
+	EventFeaturesChanged
+
+	// EventNotGenuine means IsGenuineEx() returned IGRNotGenuine (or failed
+	// with an error, in which case MonitorEvent.Err is set).
+	EventNotGenuine
+
+	// EventNotGenuineInVM means IsGenuineEx() returned IGRNotGenuineInVM.
+	EventNotGenuineInVM
+
+	// EventInternetError means IsGenuineEx() returned IGRInternetError.
+	EventInternetError
+
+	// EventGraceEntered means the activation couldn't be re-verified with the
+	// LimeLM servers and the grace period has just started.
+	EventGraceEntered
+
+	// EventGraceExpiring means the grace period is ending within
+	// MonitorOptions.GraceExpiringDays days.
+	EventGraceExpiring
+)
+
+// String returns the name of the event type.
+func (t MonitorEventType) String() string {
+	switch t {
+	case EventGenuine:
+		return "Genuine"
+	case EventFeaturesChanged:
+		return "FeaturesChanged"
+	case EventNotGenuine:
+		return "NotGenuine"
+	case EventNotGenuineInVM:
+		return "NotGenuineInVM"
+	case EventInternetError:
+		return "InternetError"
+	case EventGraceEntered:
+		return "GraceEntered"
+	case EventGraceExpiring:
+		return "GraceExpiring"
+	default:
+		return "Unknown"
+	}
+}
+
+// MonitorEvent is published by the Monitor after each check.
+type MonitorEvent struct {
+	Type MonitorEventType
+
+	// Time is when the check was made.
+	Time time.Time
+
+	// Result is the result of IsGenuineEx().
+	Result IsGenuineResult
+
+	// DaysRemaining and InGracePeriod are the result of GenuineDays().
+	// They're only set for the activated results (EventGenuine,
+	// EventFeaturesChanged, EventInternetError and the grace events).
+	DaysRemaining uint32
+	InGracePeriod bool
+
+	// Err is the error returned by IsGenuineEx(), if any.
+	Err error
+}
+
+// MonitorOptions configures a Monitor. The zero value of each field
+// means the default is used.
+type MonitorOptions struct {
+	// DaysBetweenChecks is passed to IsGenuineEx(). Defaults to 90.
+	DaysBetweenChecks uint32
+
+	// GraceDaysOnInetErr is passed to IsGenuineEx(). Defaults to 14.
+	GraceDaysOnInetErr uint32
+
+	// SkipOffline and OfflineShowInetErr are passed to IsGenuineEx().
+	SkipOffline        bool
+	OfflineShowInetErr bool
+
+	// Interval is how often IsGenuineEx() is called. Defaults to 1 hour.
+	// IsGenuineEx() only contacts the LimeLM servers every DaysBetweenChecks
+	// days, so calling it more often is cheap.
+	Interval time.Duration
+
+	// Jitter is the maximum random delay added to the first check and to
+	// every Interval, so a fleet of computers started at the same time
+	// doesn't hit the servers at the same time. Defaults to Interval / 10.
+	// Use a negative value to disable it.
+	Jitter time.Duration
+
+	// GraceExpiringDays is how many days before the end of the grace period
+	// EventGraceExpiring starts being published. Defaults to 3.
+	GraceExpiringDays uint32
+
+	// OnEvent, if set, is called (on the monitor's goroutine) with each event
+	// instead of publishing them on the Events() channel. It must not call
+	// Stop(), which waits for the monitor's goroutine and so would never
+	// return (call it on another goroutine instead: go m.Stop()).
+	OnEvent func(MonitorEvent)
+
+	// EventBuffer is the size of the Events() channel buffer. Defaults to 16.
+	EventBuffer int
+}
+
+// Monitor periodically calls IsGenuineEx() and GenuineDays() and publishes
+// the results as MonitorEvents. Create it with NewMonitor.
+type Monitor struct {
+	ta     *TurboActivate
+	opts   MonitorOptions
+	events chan MonitorEvent
+	rnd    *rand.Rand
+
+	mu      sync.Mutex
+	cancel  context.CancelFunc
+	stopped chan struct{}
+	inGrace bool
+}
+
+// ErrMonitorRunning is returned by Monitor.Start() if the monitor is already running.
+var ErrMonitorRunning = errors.New("The monitor is already running")
+
+// NewMonitor creates a Monitor that checks ta with the options. Call Start()
+// to start the checks.
+func NewMonitor(ta *TurboActivate, opts MonitorOptions) *Monitor {
+	if opts.DaysBetweenChecks == 0 {
+		opts.DaysBetweenChecks = 90
+	}
+
+	if opts.GraceDaysOnInetErr == 0 {
+		opts.GraceDaysOnInetErr = 14
+	}
+
+	if opts.Interval <= 0 {
+		opts.Interval = time.Hour
+	}
+
+	if opts.Jitter == 0 {
+		opts.Jitter = opts.Interval / 10
+	} else if opts.Jitter < 0 {
+		opts.Jitter = 0
+	}
+
+	if opts.GraceExpiringDays == 0 {
+		opts.GraceExpiringDays = 3
+	}
+
+	if opts.EventBuffer <= 0 {
+		opts.EventBuffer = 16
+	}
+
+	return &Monitor{
+		ta:     ta,
+		opts:   opts,
+		events: make(chan MonitorEvent, opts.EventBuffer),
+		rnd:    rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// Events returns the channel the events are published on (unless
+// MonitorOptions.OnEvent is set). If the channel isn't drained the monitor
+// waits until it is (or until Stop() is called).
+func (m *Monitor) Events() <-chan MonitorEvent {
+	return m.events
+}
+
+// Start starts the checks on a new goroutine. The first check is made
+// after a random delay of up to MonitorOptions.Jitter.
+func (m *Monitor) Start() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.cancel != nil {
+		return ErrMonitorRunning
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	m.cancel = cancel
+	m.stopped = make(chan struct{})
+
+	go m.run(ctx, m.stopped)
+
+	return nil
+}
+
+// Stop stops the checks and waits for the monitor's goroutine to exit.
+// A check that's waiting on the LimeLM servers is abandoned (see ActivateContext).
+// Don't call it from MonitorOptions.OnEvent (see there).
+func (m *Monitor) Stop() {
+	m.mu.Lock()
+	cancel, stopped := m.cancel, m.stopped
+	m.cancel, m.stopped = nil, nil
+	m.mu.Unlock()
+
+	if cancel == nil {
+		return
+	}
+
+	cancel()
+	<-stopped
+}
+
+func (m *Monitor) run(ctx context.Context, stopped chan struct{}) {
+	defer close(stopped)
+
+	timer := time.NewTimer(m.jitter())
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		m.check(ctx)
+
+		timer.Reset(m.opts.Interval + m.jitter())
+	}
+}
+
+func (m *Monitor) jitter() time.Duration {
+	if m.opts.Jitter <= 0 {
+		return 0
+	}
+
+	return time.Duration(m.rnd.Int63n(int64(m.opts.Jitter)))
+}
+
+// check runs IsGenuineEx() and GenuineDays() and publishes the events.
+func (m *Monitor) check(ctx context.Context) {
+	res, err := m.ta.IsGenuineExContext(ctx, m.opts.DaysBetweenChecks, m.opts.GraceDaysOnInetErr, m.opts.SkipOffline, m.opts.OfflineShowInetErr)
+
+	if ctx.Err() != nil {
+		return
+	}
+
+	ev := MonitorEvent{
+		Time:   time.Now(),
+		Result: res,
+		Err:    err,
+	}
+
+	switch {
+	case err != nil:
+		ev.Type = EventNotGenuine
+	case res == IGRGenuine:
+		ev.Type = EventGenuine
+	case res == IGRGenuineFeaturesChanged:
+		ev.Type = EventFeaturesChanged
+	case res == IGRNotGenuineInVM:
+		ev.Type = EventNotGenuineInVM
+	case res == IGRInternetError:
+		ev.Type = EventInternetError
+	default:
+		ev.Type = EventNotGenuine
+	}
+
+	activated := err == nil && (res == IGRGenuine || res == IGRGenuineFeaturesChanged || res == IGRInternetError)
+
+	// whether the grace period is known (it isn't if GenuineDays() fails)
+	known := true
+
+	if activated {
+		days, inGrace, derr := m.ta.GenuineDays(m.opts.DaysBetweenChecks, m.opts.GraceDaysOnInetErr)
+
+		if derr == nil {
+			ev.DaysRemaining = days
+			ev.InGracePeriod = inGrace
+		} else {
+			known = false
+		}
+	}
+
+	m.mu.Lock()
+	wasInGrace := m.inGrace
+
+	if known {
+		m.inGrace = ev.InGracePeriod
+	}
+
+	m.mu.Unlock()
+
+	if !m.publish(ctx, ev) {
+		return
+	}
+
+	if ev.InGracePeriod && !wasInGrace {
+		grace := ev
+		grace.Type = EventGraceEntered
+
+		if !m.publish(ctx, grace) {
+			return
+		}
+	}
+
+	if ev.InGracePeriod && ev.DaysRemaining <= m.opts.GraceExpiringDays {
+		expiring := ev
+		expiring.Type = EventGraceExpiring
+
+		m.publish(ctx, expiring)
+	}
+}
+
+// publish delivers the event. Returns false if the monitor was stopped first.
+func (m *Monitor) publish(ctx context.Context, ev MonitorEvent) bool {
+	if m.opts.OnEvent != nil {
+		m.opts.OnEvent(ev)
+		return true
+	}
+
+	select {
+	case m.events <- ev:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
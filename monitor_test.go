@@ -0,0 +1,117 @@
+// Copyright 2018 wyDay, LLC. All rights reserved.
+
+package turboactivate_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"golang.wyday.com/turboactivate"
+	"golang.wyday.com/turboactivate/fake"
+	"golang.wyday.com/turboactivate/internal/tatest"
+)
+
+// checkOnce starts m, which checks right away, and returns the n events of
+// that check.
+func checkOnce(t *testing.T, m *turboactivate.Monitor, n int) []turboactivate.MonitorEvent {
+	t.Helper()
+
+	if err := m.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	defer m.Stop()
+
+	var events []turboactivate.MonitorEvent
+
+	for len(events) < n {
+		select {
+		case ev := <-m.Events():
+			events = append(events, ev)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("got %d events; want %d", len(events), n)
+		}
+	}
+
+	return events
+}
+
+func TestMonitor(t *testing.T) {
+	b := fake.New()
+
+	var clock = fake.NewManualClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	b.SetClock(clock.Now)
+
+	ta := tatest.New(t, b, tatest.WithActivation(""))
+
+	// the interval is long enough that every Start() makes a single check
+	var m = turboactivate.NewMonitor(ta, turboactivate.MonitorOptions{Interval: time.Hour, Jitter: -1})
+
+	if ev := checkOnce(t, m, 1)[0]; ev.Type != turboactivate.EventGenuine || ev.DaysRemaining != 90 || ev.InGracePeriod {
+		t.Errorf("event = %+v; want Genuine with 90 days", ev)
+	}
+
+	// the servers can't be reached when the activation must be verified again
+	b.SetOnline(false)
+	clock.AdvanceDays(91)
+
+	var events = checkOnce(t, m, 2)
+
+	if events[0].Type != turboactivate.EventInternetError || events[1].Type != turboactivate.EventGraceEntered || events[1].DaysRemaining != 14 {
+		t.Errorf("events = %+v; want InternetError and GraceEntered", events)
+	}
+
+	// an error getting the days left doesn't leave the grace period
+	b.FailNext("GenuineDays", turboactivate.TAEPermission)
+
+	if ev := checkOnce(t, m, 1)[0]; ev.Type != turboactivate.EventInternetError {
+		t.Errorf("event = %+v; want InternetError", ev)
+	}
+
+	clock.AdvanceDays(12)
+
+	events = checkOnce(t, m, 2)
+
+	if events[0].Type != turboactivate.EventInternetError || events[1].Type != turboactivate.EventGraceExpiring || events[1].DaysRemaining != 2 {
+		t.Errorf("events = %+v; want InternetError and GraceExpiring", events)
+	}
+
+	b.FailNext("IsGenuineEx", turboactivate.TAEPermission)
+
+	if ev := checkOnce(t, m, 1)[0]; ev.Type != turboactivate.EventNotGenuine || !errors.Is(ev.Err, turboactivate.ErrPermission) {
+		t.Errorf("event = %+v; want NotGenuine with the error", ev)
+	}
+}
+
+func TestMonitorStartStop(t *testing.T) {
+	ta := tatest.New(t, fake.New())
+
+	var events = make(chan turboactivate.MonitorEvent, 1)
+
+	var m = turboactivate.NewMonitor(ta, turboactivate.MonitorOptions{
+		Interval: time.Hour,
+		Jitter:   -1,
+		OnEvent:  func(ev turboactivate.MonitorEvent) { events <- ev },
+	})
+
+	if err := m.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.Start(); !errors.Is(err, turboactivate.ErrMonitorRunning) {
+		t.Errorf("Start() twice = %v; want ErrMonitorRunning", err)
+	}
+
+	select {
+	case ev := <-events:
+		if ev.Type != turboactivate.EventNotGenuine || ev.Err != nil {
+			t.Errorf("event = %+v; want NotGenuine", ev)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event")
+	}
+
+	m.Stop()
+	m.Stop()
+}
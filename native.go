@@ -0,0 +1,441 @@
+// Copyright 2018 wyDay, LLC. All rights reserved.
+
+// +build cgo,!turboactivate_nonative,!turboactivate_dynamic
+
+package turboactivate // import "golang.wyday.com/turboactivate"
+
+/*
+#cgo CFLAGS: -I .
+#cgo LDFLAGS: -L . -L .. -lTurboActivate
+
+#include <stdlib.h>
+#include "TurboActivate.h"
+
+// taTrialCallbackGo is exported from native_callback.go.
+extern void taTrialCallbackGo(uint32_t status, uintptr_t slot);
+
+// taTrialCallback passes the trial callbacks on to Go. The user
+// defined pointer is the slot of the handle the callback was set for.
+static void TA_CC taTrialCallback(uint32_t status, void * userDefinedPtr)
+{
+	taTrialCallbackGo(status, (uintptr_t)userDefinedPtr);
+}
+
+static HRESULT taSetTrialCallback(uint32_t handle, uintptr_t slot)
+{
+	return TA_SetTrialCallback(handle, taTrialCallback, (void *)slot);
+}
+*/
+import "C"
+import (
+	"unsafe"
+)
+
+// nativeBackend is the Backend that calls into the TurboActivate library through cgo.
+type nativeBackend struct{}
+
+func init() {
+	defaultBackend = nativeBackend{}
+}
+
+func (nativeBackend) PDetsFromPath(filename string) HRESULT {
+	var nativeFilename = getTAStrPtr(filename)
+
+	var ret = C.TA_PDetsFromPath(nativeFilename)
+
+	C.free(unsafe.Pointer(nativeFilename))
+
+	return HRESULT(ret)
+}
+
+func (nativeBackend) PDetsFromByteArray(data []byte) HRESULT {
+
+	if len(data) == 0 {
+		// TA_E_INVALID_ARGS
+		return 0x13
+	}
+
+	var ret = C.TA_PDetsFromByteArray((*C.uint8_t)(unsafe.Pointer(&data[0])), C.size_t(len(data)))
+
+	return HRESULT(ret)
+}
+
+func (nativeBackend) GetHandle(versionGUID string) uint32 {
+	var nativeTaGUID = getTAStrPtr(versionGUID)
+
+	var handl C.uint32_t = C.TA_GetHandle(nativeTaGUID)
+
+	C.free(unsafe.Pointer(nativeTaGUID))
+
+	return uint32(handl)
+}
+
+func (nativeBackend) GetVersion() (uint32, uint32, uint32, uint32, HRESULT) {
+
+	var major, minor, build, revision C.uint32_t
+
+	var ret = C.TA_GetVersion(&major, &minor, &build, &revision)
+
+	return uint32(major), uint32(minor), uint32(build), uint32(revision), HRESULT(ret)
+}
+
+func (nativeBackend) Cleanup() HRESULT {
+
+	var ret = C.TA_Cleanup()
+
+	// the handles (and so their callbacks) are gone
+	resetNativeTrialCallbacks(nativeBackend{})
+
+	return HRESULT(ret)
+}
+
+func (nativeBackend) Activate(handle uint32, extraData string) HRESULT {
+
+	var ret C.HRESULT
+
+	if extraData == "" {
+		ret = C.TA_Activate(C.uint32_t(handle), (*C.ACTIVATE_OPTIONS)(nil))
+	} else {
+
+		var actOptions C.ACTIVATE_OPTIONS
+		actOptions.nLength = C.uint32_t(unsafe.Sizeof(actOptions))
+		actOptions.sExtraData = (C.STRCTYPE)(getTAStrPtr(extraData))
+
+		ret = C.TA_Activate(C.uint32_t(handle), (*C.ACTIVATE_OPTIONS)(unsafe.Pointer(&actOptions)))
+
+		C.free(unsafe.Pointer(actOptions.sExtraData))
+	}
+
+	return HRESULT(ret)
+}
+
+func (nativeBackend) ActivationRequestToFile(handle uint32, filename string, extraData string) HRESULT {
+
+	var nativeFilename = getTAStrPtr(filename)
+
+	var ret C.HRESULT
+
+	if extraData == "" {
+		ret = C.TA_ActivationRequestToFile(C.uint32_t(handle), nativeFilename, (*C.ACTIVATE_OPTIONS)(nil))
+	} else {
+
+		var actOptions C.ACTIVATE_OPTIONS
+
+		actOptions.nLength = C.uint32_t(unsafe.Sizeof(actOptions))
+		actOptions.sExtraData = (C.STRCTYPE)(getTAStrPtr(extraData))
+
+		ret = C.TA_ActivationRequestToFile(C.uint32_t(handle), nativeFilename, (*C.ACTIVATE_OPTIONS)(unsafe.Pointer(&actOptions)))
+
+		C.free(unsafe.Pointer(actOptions.sExtraData))
+	}
+
+	C.free(unsafe.Pointer(nativeFilename))
+
+	return HRESULT(ret)
+}
+
+func (nativeBackend) ActivateFromFile(handle uint32, filename string) HRESULT {
+
+	var nativeFilename = getTAStrPtr(filename)
+
+	var ret C.HRESULT = C.TA_ActivateFromFile(C.uint32_t(handle), nativeFilename)
+
+	C.free(unsafe.Pointer(nativeFilename))
+
+	return HRESULT(ret)
+}
+
+func (nativeBackend) CheckAndSavePKey(handle uint32, productKey string, flags TAFlags) HRESULT {
+
+	var nativeProductKey = getTAStrPtr(productKey)
+
+	var ret C.HRESULT = C.TA_CheckAndSavePKey(C.uint32_t(handle), nativeProductKey, C.uint32_t(flags))
+
+	C.free(unsafe.Pointer(nativeProductKey))
+
+	return HRESULT(ret)
+}
+
+func (nativeBackend) Deactivate(handle uint32, eraseProductKey bool) HRESULT {
+
+	var erasePK C.char
+
+	if eraseProductKey {
+		erasePK = 1
+	} else {
+		erasePK = 0
+	}
+
+	return HRESULT(C.TA_Deactivate(C.uint32_t(handle), erasePK))
+}
+
+func (nativeBackend) DeactivationRequestToFile(handle uint32, filename string, eraseProductKey bool) HRESULT {
+	var erasePK C.char
+
+	if eraseProductKey {
+		erasePK = 1
+	} else {
+		erasePK = 0
+	}
+
+	var nativeFilename = getTAStrPtr(filename)
+
+	var ret C.HRESULT = C.TA_DeactivationRequestToFile(C.uint32_t(handle), nativeFilename, erasePK)
+
+	C.free(unsafe.Pointer(nativeFilename))
+
+	return HRESULT(ret)
+}
+
+func (nativeBackend) GetExtraData(handle uint32) (string, HRESULT) {
+
+	var ret C.HRESULT = C.TA_GetExtraData(C.uint32_t(handle), nil, 0)
+
+	var nativeExtraData = getTAStrBufferPtr(C.size_t(ret))
+
+	ret = C.TA_GetExtraData(C.uint32_t(handle), nativeExtraData, C.int(ret))
+
+	var extraData string
+
+	// TA_OK
+	if ret == 0x00 {
+		extraData = stringFromTAStrPtr(nativeExtraData)
+	}
+
+	C.free(unsafe.Pointer(nativeExtraData))
+
+	return extraData, HRESULT(ret)
+}
+
+func (nativeBackend) GetFeatureValue(handle uint32, featureName string) (string, HRESULT) {
+
+	var nativeFeatureName = getTAStrPtr(featureName)
+
+	var ret C.HRESULT = C.TA_GetFeatureValue(C.uint32_t(handle), nativeFeatureName, nil, 0)
+
+	var nativeFeatureValue = getTAStrBufferPtr(C.size_t(ret))
+
+	ret = C.TA_GetFeatureValue(C.uint32_t(handle), nativeFeatureName, nativeFeatureValue, C.int(ret))
+
+	C.free(unsafe.Pointer(nativeFeatureName))
+
+	var featureValue string
+
+	// TA_OK
+	if ret == 0x00 {
+		featureValue = stringFromTAStrPtr(nativeFeatureValue)
+	}
+
+	C.free(unsafe.Pointer(nativeFeatureValue))
+
+	return featureValue, HRESULT(ret)
+}
+
+func (nativeBackend) GetPKey(handle uint32) (string, HRESULT) {
+	var nativePkey = getTAStrBufferPtr(35)
+
+	var ret C.HRESULT = C.TA_GetPKey(C.uint32_t(handle), nativePkey, 35)
+
+	var pkey string
+
+	// TA_OK
+	if ret == 0x00 {
+		pkey = stringFromTAStrPtr(nativePkey)
+	}
+
+	C.free(unsafe.Pointer(nativePkey))
+
+	return pkey, HRESULT(ret)
+}
+
+func (nativeBackend) IsActivated(handle uint32) HRESULT {
+	return HRESULT(C.TA_IsActivated(C.uint32_t(handle)))
+}
+
+func (nativeBackend) IsDateValid(handle uint32, dateTime string, flags TADateCheckFlags) HRESULT {
+	var nativeDateTime = getTAStrPtr(dateTime)
+
+	var ret C.HRESULT = C.TA_IsDateValid(C.uint32_t(handle), nativeDateTime, C.uint32_t(flags))
+
+	C.free(unsafe.Pointer(nativeDateTime))
+
+	return HRESULT(ret)
+}
+
+func (nativeBackend) IsGenuine(handle uint32) HRESULT {
+	return HRESULT(C.TA_IsGenuine(C.uint32_t(handle)))
+}
+
+func (nativeBackend) IsGenuineEx(handle uint32, daysBetweenChecks uint32, graceDaysOnInetErr uint32, skipOffline bool, offlineShowInetErr bool) HRESULT {
+
+	var genOpts C.GENUINE_OPTIONS
+
+	genOpts.nLength = C.uint32_t(unsafe.Sizeof(genOpts))
+
+	if skipOffline {
+		// TA_SKIP_OFFLINE
+		genOpts.flags = 1
+
+		if offlineShowInetErr {
+			// TA_OFFLINE_SHOW_INET_ERR
+			genOpts.flags |= 2
+		}
+	} else {
+		genOpts.flags = 0
+	}
+
+	genOpts.nDaysBetweenChecks = C.uint32_t(daysBetweenChecks)
+	genOpts.nGraceDaysOnInetErr = C.uint32_t(graceDaysOnInetErr)
+
+	return HRESULT(C.TA_IsGenuineEx(C.uint32_t(handle), (*C.GENUINE_OPTIONS)(unsafe.Pointer(&genOpts))))
+}
+
+func (nativeBackend) GenuineDays(handle uint32, daysBetweenChecks uint32, graceDaysOnInetErr uint32) (uint32, bool, HRESULT) {
+
+	var daysRemain C.uint32_t
+	var inGrace C.char
+
+	var ret C.HRESULT = C.TA_GenuineDays(C.uint32_t(handle), C.uint32_t(daysBetweenChecks), C.uint32_t(graceDaysOnInetErr), (*C.uint32_t)(unsafe.Pointer(&daysRemain)), (*C.char)(unsafe.Pointer(&inGrace)))
+
+	return uint32(daysRemain), inGrace == 1, HRESULT(ret)
+}
+
+func (nativeBackend) IsProductKeyValid(handle uint32) HRESULT {
+	return HRESULT(C.TA_IsProductKeyValid(C.uint32_t(handle)))
+}
+
+func (nativeBackend) SetCustomProxy(proxy string) HRESULT {
+
+	var nativeProxy = getTAStrPtr(proxy)
+
+	var ret C.HRESULT = C.TA_SetCustomProxy(nativeProxy)
+
+	C.free(unsafe.Pointer(nativeProxy))
+
+	return HRESULT(ret)
+}
+
+func (nativeBackend) TrialDaysRemaining(handle uint32, flags TAFlags) (uint32, HRESULT) {
+
+	var daysRemain C.uint32_t
+
+	var ret C.HRESULT = C.TA_TrialDaysRemaining(C.uint32_t(handle), C.uint32_t(flags), (*C.uint32_t)(unsafe.Pointer(&daysRemain)))
+
+	return uint32(daysRemain), HRESULT(ret)
+}
+
+func (nativeBackend) UseTrial(handle uint32, flags TAFlags, extraData string) HRESULT {
+
+	var ret C.HRESULT
+
+	if extraData == "" {
+		ret = C.TA_UseTrial(C.uint32_t(handle), C.uint32_t(flags), nil)
+	} else {
+		var nativeExtraData = getTAStrPtr(extraData)
+
+		ret = C.TA_UseTrial(C.uint32_t(handle), C.uint32_t(flags), nativeExtraData)
+
+		C.free(unsafe.Pointer(nativeExtraData))
+	}
+
+	return HRESULT(ret)
+}
+
+func (nativeBackend) UseTrialVerifiedRequest(handle uint32, filename string, extraData string) HRESULT {
+
+	var ret C.HRESULT
+
+	var nativeFilename = getTAStrPtr(filename)
+
+	if extraData == "" {
+		ret = C.TA_UseTrialVerifiedRequest(C.uint32_t(handle), nativeFilename, nil)
+	} else {
+		var nativeExtraData = getTAStrPtr(extraData)
+
+		ret = C.TA_UseTrialVerifiedRequest(C.uint32_t(handle), nativeFilename, nativeExtraData)
+
+		C.free(unsafe.Pointer(nativeExtraData))
+	}
+
+	C.free(unsafe.Pointer(nativeFilename))
+
+	return HRESULT(ret)
+}
+
+func (nativeBackend) UseTrialVerifiedFromFile(handle uint32, filename string, flags TAFlags) HRESULT {
+
+	var nativeFilename = getTAStrPtr(filename)
+
+	var ret C.HRESULT = C.TA_UseTrialVerifiedFromFile(C.uint32_t(handle), nativeFilename, C.uint32_t(flags))
+
+	C.free(unsafe.Pointer(nativeFilename))
+
+	return HRESULT(ret)
+}
+
+func (nativeBackend) ExtendTrial(handle uint32, flags TAFlags, trialExtension string) HRESULT {
+
+	var nativeTrialExtension = getTAStrPtr(trialExtension)
+
+	var ret C.HRESULT = C.TA_ExtendTrial(C.uint32_t(handle), C.uint32_t(flags), nativeTrialExtension)
+
+	C.free(unsafe.Pointer(nativeTrialExtension))
+
+	return HRESULT(ret)
+}
+
+func (nativeBackend) SetCustomActDataPath(handle uint32, directory string) HRESULT {
+
+	var nativeDirectory = getTAStrPtr(directory)
+
+	var ret C.HRESULT = C.TA_SetCustomActDataPath(C.uint32_t(handle), nativeDirectory)
+
+	C.free(unsafe.Pointer(nativeDirectory))
+
+	return HRESULT(ret)
+}
+
+func (n nativeBackend) SetTrialCallback(handle uint32, callback func(status uint32)) HRESULT {
+
+	if callback == nil {
+		// the native callback stays set, but taTrialCallbackGo ignores it
+		setNativeTrialCallback(n, handle, nil)
+		return 0x00
+	}
+
+	var slot = setNativeTrialCallback(n, handle, callback)
+
+	var ret = C.taSetTrialCallback(C.uint32_t(handle), C.uintptr_t(slot))
+
+	if ret != 0x00 {
+		setNativeTrialCallback(n, handle, nil)
+	}
+
+	return HRESULT(ret)
+}
+
+func (nativeBackend) BlackListKeys(handle uint32, keys []string) HRESULT {
+
+	if len(keys) == 0 {
+		// TA_E_INVALID_ARGS
+		return 0x13
+	}
+
+	// the array of string pointers must be in C memory
+	var nativeKeys = C.calloc(C.size_t(len(keys)), C.size_t(unsafe.Sizeof(uintptr(0))))
+	var keyPtrs = (*[1 << 28]TAStrPtrType)(nativeKeys)[:len(keys):len(keys)]
+
+	for i, key := range keys {
+		keyPtrs[i] = getTAStrPtr(key)
+	}
+
+	var ret = C.TA_BlackListKeys(C.uint32_t(handle), (*C.STRCTYPE)(nativeKeys), C.uint32_t(len(keys)))
+
+	for _, p := range keyPtrs {
+		C.free(unsafe.Pointer(p))
+	}
+
+	C.free(nativeKeys)
+
+	return HRESULT(ret)
+}
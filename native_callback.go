@@ -0,0 +1,19 @@
+// Copyright 2018 wyDay, LLC. All rights reserved.
+
+// +build cgo
+
+package turboactivate // import "golang.wyday.com/turboactivate"
+
+/*
+#include <stdint.h>
+*/
+import "C"
+
+// taTrialCallbackGo is called by the TurboActivate library (through the
+// taTrialCallback shim in native.go, or ta_trial_callback in
+// procbackend_cgo.go) when the trial of the handle with the slot changes.
+//
+//export taTrialCallbackGo
+func taTrialCallbackGo(status C.uint32_t, slot C.uintptr_t) {
+	nativeTrialCallback(uint32(status), uintptr(slot))
+}
@@ -0,0 +1,112 @@
+// Copyright 2018 wyDay, LLC. All rights reserved.
+
+package turboactivate // import "golang.wyday.com/turboactivate"
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// ActivationRequest writes the "activation request" for offline activation
+// to w. It's like ActivationRequestToFile, but the request can be sent over
+// HTTP, the clipboard, a message queue, etc. without saving it anywhere.
+func (ta *TurboActivate) ActivationRequest(w io.Writer, extraData string) error {
+	return requestTo(w, func(filename string) error {
+		return ta.ActivationRequestToFile(filename, extraData)
+	})
+}
+
+// ActivateFrom activates from the "activation response" read from r.
+// It's like ActivateFromFile, but the response doesn't need to be saved first.
+func (ta *TurboActivate) ActivateFrom(r io.Reader) error {
+	return responseFrom(r, ta.ActivateFromFile)
+}
+
+// DeactivationRequest writes the "deactivation request" for offline
+// deactivation to w. It's like DeactivationRequestToFile.
+func (ta *TurboActivate) DeactivationRequest(w io.Writer, eraseProductKey bool) error {
+	return requestTo(w, func(filename string) error {
+		return ta.DeactivationRequestToFile(filename, eraseProductKey)
+	})
+}
+
+// UseTrialVerifiedRequestTo writes the "verified trial" offline request to w.
+// It's like UseTrialVerifiedRequest. Use UseTrialVerifiedFrom() with the
+// response from LimeLM to actually start the trial.
+func (ta *TurboActivate) UseTrialVerifiedRequestTo(w io.Writer, extraData string) error {
+	return requestTo(w, func(filename string) error {
+		return ta.UseTrialVerifiedRequest(filename, extraData)
+	})
+}
+
+// UseTrialVerifiedFrom uses the "verified trial response" read from r to
+// start the verified trial. It's like UseTrialVerifiedFromFile.
+func (ta *TurboActivate) UseTrialVerifiedFrom(r io.Reader, flags TAFlags) error {
+	return responseFrom(r, func(filename string) error {
+		return ta.UseTrialVerifiedFromFile(filename, flags)
+	})
+}
+
+// withTempDir calls fn with a new private directory (only readable by the
+// current user) and removes the directory and everything in it afterwards.
+//
+// The TurboActivate library only reads and writes the offline requests and
+// responses as files, so they're passed through this directory.
+func withTempDir(fn func(dir string) error) error {
+	dir, err := os.MkdirTemp("", "turboactivate-")
+
+	if err != nil {
+		return err
+	}
+
+	defer os.RemoveAll(dir)
+
+	return fn(dir)
+}
+
+// requestTo has the library write a request file with write, and copies it to w.
+func requestTo(w io.Writer, write func(filename string) error) error {
+	return withTempDir(func(dir string) error {
+		var filename = filepath.Join(dir, "request.xml")
+
+		if err := write(filename); err != nil {
+			return err
+		}
+
+		f, err := os.Open(filename)
+
+		if err != nil {
+			return err
+		}
+
+		defer f.Close()
+
+		_, err = io.Copy(w, f)
+		return err
+	})
+}
+
+// responseFrom copies r to a response file and has the library read it with read.
+func responseFrom(r io.Reader, read func(filename string) error) error {
+	return withTempDir(func(dir string) error {
+		var filename = filepath.Join(dir, "response.xml")
+
+		f, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+
+		if err != nil {
+			return err
+		}
+
+		if _, err = io.Copy(f, r); err != nil {
+			f.Close()
+			return err
+		}
+
+		if err = f.Close(); err != nil {
+			return err
+		}
+
+		return read(filename)
+	})
+}
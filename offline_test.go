@@ -0,0 +1,108 @@
+// Copyright 2018 wyDay, LLC. All rights reserved.
+
+package turboactivate_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"golang.wyday.com/turboactivate"
+	"golang.wyday.com/turboactivate/fake"
+	"golang.wyday.com/turboactivate/internal/tatest"
+)
+
+func TestOfflineActivation(t *testing.T) {
+	b := fake.New()
+	b.SetOnline(false)
+
+	ta := tatest.New(t, b, tatest.WithKey(nil))
+
+	var req bytes.Buffer
+
+	if err := ta.ActivationRequest(&req, "offline"); err != nil {
+		t.Fatal(err)
+	}
+
+	if req.Len() == 0 {
+		t.Fatal("the activation request is empty")
+	}
+
+	// the fake's requests are also its responses
+	if err := ta.ActivateFrom(&req); err != nil {
+		t.Fatal(err)
+	}
+
+	if ok, err := ta.IsActivated(); !ok || err != nil {
+		t.Errorf("IsActivated() = %v, %v; want true", ok, err)
+	}
+
+	var deact bytes.Buffer
+
+	if err := ta.DeactivationRequest(&deact, false); err != nil || deact.Len() == 0 {
+		t.Errorf("DeactivationRequest() = %v, with %d bytes", err, deact.Len())
+	}
+
+	if ok, _ := ta.IsActivated(); ok {
+		t.Error("the product is still activated after the deactivation request")
+	}
+}
+
+func TestOfflineVerifiedTrial(t *testing.T) {
+	b := fake.New()
+	b.AddProduct(testGUID, fake.Product{TrialDays: 10})
+	b.SetOnline(false)
+
+	ta, err := turboactivate.New(testGUID, turboactivate.WithBackend(b))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer ta.Close()
+
+	var flags = turboactivate.TAUser | turboactivate.TAVerifiedTrial
+	var req bytes.Buffer
+
+	if err = ta.UseTrialVerifiedRequestTo(&req, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = ta.UseTrialVerifiedFrom(&req, flags); err != nil {
+		t.Fatal(err)
+	}
+
+	if days, err := ta.TrialDaysRemaining(flags); days != 10 || err != nil {
+		t.Errorf("TrialDaysRemaining() = %d, %v; want 10", days, err)
+	}
+}
+
+func TestOfflineErrors(t *testing.T) {
+	b := fake.New()
+
+	ta := tatest.New(t, b, tatest.WithKey(nil))
+
+	b.FailNext("ActivationRequestToFile", turboactivate.TAEPKey)
+
+	var req bytes.Buffer
+
+	if err := ta.ActivationRequest(&req, ""); !errors.Is(err, turboactivate.ErrInvalidPKey) || req.Len() != 0 {
+		t.Errorf("ActivationRequest() = %v, with %d bytes; want ErrInvalidPKey", err, req.Len())
+	}
+
+	var readErr = errors.New("read failed")
+
+	if err := ta.ActivateFrom(iotest.ErrReader(readErr)); !errors.Is(err, readErr) {
+		t.Errorf("ActivateFrom() of a failing reader = %v", err)
+	}
+
+	if n := b.Calls("ActivateFromFile"); n != 0 {
+		t.Errorf("ActivateFromFile was called %d times", n)
+	}
+
+	if err := ta.ActivateFrom(strings.NewReader("not a response")); err == nil {
+		t.Error("ActivateFrom() of an invalid response succeeded")
+	}
+}
@@ -0,0 +1,272 @@
+// Copyright 2018 wyDay, LLC. All rights reserved.
+
+package turboactivate // import "golang.wyday.com/turboactivate"
+
+import (
+	"errors"
+	"io/fs"
+	"log/slog"
+	"regexp"
+)
+
+// Option configures the TurboActivate instance created by New.
+type Option func(*config) error
+
+// config is the configuration built from the Options passed to New.
+type config struct {
+	backend  Backend
+	wrappers []func(Backend) Backend
+
+	pdetsFilename string
+	pdetsData     []byte
+	pdetsFS       fs.FS
+	pdetsFSName   string
+	pdetsSet      bool
+
+	customActDataPath string
+	proxy             string
+	defaultFlags      TAFlags
+	retry             *RetryPolicy
+
+	logger       *slog.Logger
+	logOK        slog.Level
+	logFailed    slog.Level
+	logLevelsSet bool
+}
+
+var (
+	// ErrInvalidVersionGUID is returned by New when the VersionGUID isn't in the
+	// format LimeLM uses (e.g. "18324776654b3946fc44a5f3.49025204").
+	ErrInvalidVersionGUID = errors.New("The VersionGUID is not valid. Copy the VersionGUID from the version page in LimeLM")
+
+	// ErrMultipleProductDetails is returned by New when more than one
+	// WithProductDetails* option is passed.
+	ErrMultipleProductDetails = errors.New("Only one WithProductDetailsFile, WithProductDetailsBytes, or WithProductDetailsFS option can be used")
+)
+
+// versionGUIDRe matches the LimeLM VersionGUIDs.
+var versionGUIDRe = regexp.MustCompile(`^[0-9A-Fa-f]+\.[0-9]+$`)
+
+// DefaultFlags are the flags used when New isn't passed WithDefaultFlags.
+var DefaultFlags = TASystem | TAVerifiedTrial
+
+func (c *config) setPDets() error {
+	if c.pdetsSet {
+		return ErrMultipleProductDetails
+	}
+
+	c.pdetsSet = true
+	return nil
+}
+
+// WithBackend makes the TurboActivate instance use backend instead of the
+// native TurboActivate library.
+func WithBackend(backend Backend) Option {
+	return func(c *config) error {
+		if backend == nil {
+			return ErrNoBackend
+		}
+
+		c.backend = backend
+		return nil
+	}
+}
+
+// WithBackendWrapper wraps the backend (the native TurboActivate library, or
+// the one passed to WithBackend) with wrap, e.g. to instrument or log the
+// calls. The wrappers are applied in the order they're passed, so the last one
+// is the outermost. They don't need to be safe for concurrent use by the same
+// handle, since the calls are serialized before they reach the wrappers.
+func WithBackendWrapper(wrap func(Backend) Backend) Option {
+	return func(c *config) error {
+		c.wrappers = append(c.wrappers, wrap)
+		return nil
+	}
+}
+
+// WithProductDetailsFile loads the product details from the TurboActivate.dat file at filename.
+func WithProductDetailsFile(filename string) Option {
+	return func(c *config) error {
+		c.pdetsFilename = filename
+		return c.setPDets()
+	}
+}
+
+// WithProductDetailsBytes loads the product details from the contents of the TurboActivate.dat file.
+func WithProductDetailsBytes(data []byte) Option {
+	return func(c *config) error {
+		c.pdetsData = data
+		return c.setPDets()
+	}
+}
+
+// WithProductDetailsFS loads the product details from the named TurboActivate.dat
+// file in fsys (e.g. an embed.FS).
+func WithProductDetailsFS(fsys fs.FS, name string) Option {
+	return func(c *config) error {
+		c.pdetsFS = fsys
+		c.pdetsFSName = name
+		return c.setPDets()
+	}
+}
+
+// WithCustomActDataPath stores the activation data files in directory
+// (see SetCustomActDataPath).
+func WithCustomActDataPath(directory string) Option {
+	return func(c *config) error {
+		c.customActDataPath = directory
+		return nil
+	}
+}
+
+// WithProxy sets the custom proxy used by the functions that connect to the
+// internet (see SetCustomProxy).
+func WithProxy(proxy string) Option {
+	return func(c *config) error {
+		c.proxy = proxy
+		return nil
+	}
+}
+
+// WithDefaultFlags sets the flags returned by DefaultFlags(). They must
+// contain either TASystem or TAUser (not both), and at most one of
+// TAVerifiedTrial and TAUnverifiedTrial.
+func WithDefaultFlags(flags TAFlags) Option {
+	return func(c *config) error {
+		if err := validateFlags(flags, "WithDefaultFlags"); err != nil {
+			return err
+		}
+
+		c.defaultFlags = flags
+		return nil
+	}
+}
+
+// validateFlags checks the flags are a valid combination.
+func validateFlags(flags TAFlags, funcName string) error {
+	var known = TASystem | TAUser | TADisallowVM | TAUnverifiedTrial | TAVerifiedTrial
+
+	if flags&^known != 0 || (flags&TASystem != 0) == (flags&TAUser != 0) {
+		return newTAError(TAEInvalidFlags, funcName)
+	}
+
+	if flags&TAVerifiedTrial != 0 && flags&TAUnverifiedTrial != 0 {
+		return newTAError(TAEMustSpecifyTrialType, funcName)
+	}
+
+	return nil
+}
+
+// New creates a new TurboActivate instance for the VersionGUID, configured
+// with the options. For example:
+//
+//	ta, err := turboactivate.New("18324776654b3946fc44a5f3.49025204",
+//		turboactivate.WithProductDetailsFile("TurboActivate.dat"),
+//		turboactivate.WithProxy("http://proxy.example.com:8080"))
+//
+// Unlike NewTurboActivate, New checks that the VersionGUID is well formed and
+// that the TurboActivate library returned a valid handle for it. The settings
+// are applied in the order the TurboActivate library requires: the product
+// details are loaded first, then the handle is created, then the custom
+// activation data path is set (before any other function is called), and
+// finally the proxy.
+func New(versionGUID string, opts ...Option) (*TurboActivate, error) {
+
+	var c = config{
+		backend:      defaultBackend,
+		defaultFlags: DefaultFlags,
+	}
+
+	for _, opt := range opts {
+		if err := opt(&c); err != nil {
+			return nil, err
+		}
+	}
+
+	if c.backend == nil {
+		return nil, defaultBackendErr
+	}
+
+	var root = c.backend
+
+	if c.logger != nil {
+		var l = loggingBackend{b: c.backend, logger: c.logger, okLevel: slog.LevelDebug, failLevel: slog.LevelWarn}
+
+		if c.logLevelsSet {
+			l.okLevel = c.logOK
+			l.failLevel = c.logFailed
+		}
+
+		c.backend = l
+	}
+
+	for _, wrap := range c.wrappers {
+		c.backend = wrap(c.backend)
+	}
+
+	c.backend = serializeAs(c.backend, root)
+
+	if !versionGUIDRe.MatchString(versionGUID) {
+		return nil, ErrInvalidVersionGUID
+	}
+
+	// load the product details
+	var err error
+
+	switch {
+	case !c.pdetsSet:
+
+	case c.pdetsFS != nil:
+		var data []byte
+
+		if data, err = fs.ReadFile(c.pdetsFS, c.pdetsFSName); err == nil {
+			err = pdetsResultToErr(c.backend.PDetsFromByteArray(data), "PDetsFromByteArray")
+		}
+
+	case c.pdetsFilename != "":
+		err = pdetsResultToErr(c.backend.PDetsFromPath(c.pdetsFilename), "PDetsFromPath")
+
+	default:
+		err = pdetsResultToErr(c.backend.PDetsFromByteArray(c.pdetsData), "PDetsFromByteArray")
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	var ta = newTurboActivate(c.backend, versionGUID)
+
+	if ta.handle == 0 {
+		return nil, newTAError(TAEInvalidHandle, "GetHandle")
+	}
+
+	ta.defaultFlags = c.defaultFlags
+	ta.retry = c.retry
+	ta.state.logger = c.logger
+
+	if c.customActDataPath != "" {
+		if err = ta.SetCustomActDataPath(c.customActDataPath); err != nil {
+			ta.Close()
+			return nil, err
+		}
+	}
+
+	if c.proxy != "" {
+		if err = ta.SetCustomProxy(c.proxy); err != nil {
+			ta.Close()
+			return nil, err
+		}
+	}
+
+	return ta, nil
+}
+
+// DefaultFlags returns the flags set with WithDefaultFlags (or the
+// package's DefaultFlags). Pass them to the functions that take TAFlags.
+func (ta *TurboActivate) DefaultFlags() TAFlags {
+	if ta.defaultFlags == 0 {
+		return DefaultFlags
+	}
+
+	return ta.defaultFlags
+}
@@ -0,0 +1,171 @@
+// Copyright 2018 wyDay, LLC. All rights reserved.
+
+package turboactivate_test
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"golang.wyday.com/turboactivate"
+	"golang.wyday.com/turboactivate/fake"
+)
+
+func TestProductDetails(t *testing.T) {
+	var dat = []byte("TurboActivate.dat")
+
+	for _, tt := range []struct {
+		name string
+		opt  turboactivate.Option
+		call string
+	}{
+		{"file", turboactivate.WithProductDetailsFile("TurboActivate.dat"), "PDetsFromPath"},
+		{"bytes", turboactivate.WithProductDetailsBytes(dat), "PDetsFromByteArray"},
+		{"fs", turboactivate.WithProductDetailsFS(fstest.MapFS{"dat/TurboActivate.dat": {Data: dat}}, "dat/TurboActivate.dat"), "PDetsFromByteArray"},
+	} {
+		b := fake.New()
+
+		ta, err := turboactivate.New(testGUID, turboactivate.WithBackend(b), tt.opt)
+
+		if err != nil {
+			t.Errorf("%s: New() = %v", tt.name, err)
+			continue
+		}
+
+		ta.Close()
+
+		if n := b.Calls(tt.call); n != 1 {
+			t.Errorf("%s: %s was called %d times; want once", tt.name, tt.call, n)
+		}
+	}
+}
+
+func TestProductDetailsErrors(t *testing.T) {
+	b := fake.New()
+
+	_, err := turboactivate.New(testGUID, turboactivate.WithBackend(b), turboactivate.WithProductDetailsBytes(nil))
+
+	var taErr *turboactivate.TAError
+
+	if !errors.As(err, &taErr) || taErr.Code != turboactivate.TAEInvalidArgs || taErr.Func != "PDetsFromByteArray" {
+		t.Errorf("New() with empty product details = %v; want TA_E_INVALID_ARGS", err)
+	}
+
+	if _, err = turboactivate.New(testGUID, turboactivate.WithBackend(b), turboactivate.WithProductDetailsFS(fstest.MapFS{}, "TurboActivate.dat")); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("New() with a missing file = %v; want ErrNotExist", err)
+	}
+
+	_, err = turboactivate.New(testGUID, turboactivate.WithBackend(b),
+		turboactivate.WithProductDetailsFile("TurboActivate.dat"), turboactivate.WithProductDetailsBytes([]byte("dat")))
+
+	if !errors.Is(err, turboactivate.ErrMultipleProductDetails) {
+		t.Errorf("New() with two product details = %v; want ErrMultipleProductDetails", err)
+	}
+
+	// TA_FAIL means the product details were already loaded
+	b.FailNext("PDetsFromPath", turboactivate.TAFail)
+
+	ta, err := turboactivate.New(testGUID, turboactivate.WithBackend(b), turboactivate.WithProductDetailsFile("TurboActivate.dat"))
+
+	if err != nil {
+		t.Fatalf("New() when the product details are loaded = %v", err)
+	}
+
+	ta.Close()
+}
+
+func TestNewTurboActivateFromBytesWithoutLibrary(t *testing.T) {
+
+	if turboactivate.DefaultBackend() != nil {
+		t.Skip("built with the TurboActivate library")
+	}
+
+	if _, err := turboactivate.NewTurboActivateFromBytes(testGUID, []byte("dat")); err == nil {
+		t.Error("NewTurboActivateFromBytes() without the library succeeded")
+	}
+}
+
+func TestNewValidation(t *testing.T) {
+	b := fake.New()
+
+	for _, guid := range []string{"", "not a guid", "18324776654b3946fc44a5f3", "18324776654b3946fc44a5f3.4902520x"} {
+		if _, err := turboactivate.New(guid, turboactivate.WithBackend(b)); !errors.Is(err, turboactivate.ErrInvalidVersionGUID) {
+			t.Errorf("New(%q) = %v; want ErrInvalidVersionGUID", guid, err)
+		}
+	}
+
+	if _, err := turboactivate.New(testGUID, turboactivate.WithBackend(nil)); !errors.Is(err, turboactivate.ErrNoBackend) {
+		t.Errorf("New() with a nil backend = %v; want ErrNoBackend", err)
+	}
+
+	for flags, want := range map[turboactivate.TAFlags]error{
+		turboactivate.TASystem | turboactivate.TAUser:                                          turboactivate.ErrInvalidFlags,
+		turboactivate.TAVerifiedTrial:                                                          turboactivate.ErrInvalidFlags,
+		turboactivate.TAUser | turboactivate.TAVerifiedTrial | turboactivate.TAUnverifiedTrial: turboactivate.ErrMustSpecifyTrialType,
+	} {
+		if _, err := turboactivate.New(testGUID, turboactivate.WithBackend(b), turboactivate.WithDefaultFlags(flags)); !errors.Is(err, want) {
+			t.Errorf("New() with the default flags %#x = %v; want %v", uint32(flags), err, want)
+		}
+	}
+
+	b.FailNext("GetHandle", turboactivate.TAFail)
+
+	if _, err := turboactivate.New(testGUID, turboactivate.WithBackend(b)); !errors.Is(err, turboactivate.ErrInvalidHandle) {
+		t.Errorf("New() without a handle = %v; want ErrInvalidHandle", err)
+	}
+
+	b.FailNext("SetCustomActDataPath", turboactivate.TAEPermission)
+
+	if _, err := turboactivate.New(testGUID, turboactivate.WithBackend(b), turboactivate.WithCustomActDataPath(t.TempDir())); !errors.Is(err, turboactivate.ErrPermission) {
+		t.Errorf("New() with a failing data path = %v; want ErrPermission", err)
+	}
+}
+
+func TestNewOptions(t *testing.T) {
+	b := fake.New()
+
+	var order []string
+
+	var wrapper = func(name string) turboactivate.Option {
+		return turboactivate.WithBackendWrapper(func(inner turboactivate.Backend) turboactivate.Backend {
+			order = append(order, name)
+			return inner
+		})
+	}
+
+	ta, err := turboactivate.New(testGUID, turboactivate.WithBackend(b),
+		turboactivate.WithProxy("http://proxy.example.com:8080"),
+		turboactivate.WithDefaultFlags(turboactivate.TAUser|turboactivate.TAUnverifiedTrial),
+		wrapper("inner"), wrapper("outer"))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer ta.Close()
+
+	if proxy := b.Proxy(); proxy != "http://proxy.example.com:8080" {
+		t.Errorf("the proxy is %q", proxy)
+	}
+
+	if flags := ta.DefaultFlags(); flags != turboactivate.TAUser|turboactivate.TAUnverifiedTrial {
+		t.Errorf("DefaultFlags() = %#x", uint32(flags))
+	}
+
+	if len(order) != 2 || order[0] != "inner" || order[1] != "outer" {
+		t.Errorf("the wrappers were applied in the order %v", order)
+	}
+
+	ta2, err := turboactivate.New(testGUID, turboactivate.WithBackend(b))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer ta2.Close()
+
+	if flags := ta2.DefaultFlags(); flags != turboactivate.DefaultFlags {
+		t.Errorf("DefaultFlags() without WithDefaultFlags = %#x", uint32(flags))
+	}
+}
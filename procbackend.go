@@ -0,0 +1,514 @@
+// Copyright 2018 wyDay, LLC. All rights reserved.
+
+// +build cgo windows
+
+package turboactivate // import "golang.wyday.com/turboactivate"
+
+import (
+	"unsafe"
+)
+
+// taProc is one of the TA_* functions of the TurboActivate library.
+type taProc int
+
+const (
+	procGetHandle taProc = iota
+	procPDetsFromPath
+	procPDetsFromByteArray
+	procGetVersion
+	procCleanup
+	procActivate
+	procActivationRequestToFile
+	procActivateFromFile
+	procCheckAndSavePKey
+	procDeactivate
+	procDeactivationRequestToFile
+	procGetExtraData
+	procGetFeatureValue
+	procGetPKey
+	procIsActivated
+	procIsDateValid
+	procIsGenuine
+	procIsGenuineEx
+	procGenuineDays
+	procIsProductKeyValid
+	procSetCustomProxy
+	procTrialDaysRemaining
+	procUseTrial
+	procUseTrialVerifiedRequest
+	procUseTrialVerifiedFromFile
+	procExtendTrial
+	procSetCustomActDataPath
+	procSetTrialCallback
+	procBlackListKeys
+
+	numProcs
+)
+
+// procNames are the names of the TA_* functions in the library.
+var procNames = [numProcs]string{
+	procGetHandle:                 "TA_GetHandle",
+	procPDetsFromPath:             "TA_PDetsFromPath",
+	procPDetsFromByteArray:        "TA_PDetsFromByteArray",
+	procGetVersion:                "TA_GetVersion",
+	procCleanup:                   "TA_Cleanup",
+	procActivate:                  "TA_Activate",
+	procActivationRequestToFile:   "TA_ActivationRequestToFile",
+	procActivateFromFile:          "TA_ActivateFromFile",
+	procCheckAndSavePKey:          "TA_CheckAndSavePKey",
+	procDeactivate:                "TA_Deactivate",
+	procDeactivationRequestToFile: "TA_DeactivationRequestToFile",
+	procGetExtraData:              "TA_GetExtraData",
+	procGetFeatureValue:           "TA_GetFeatureValue",
+	procGetPKey:                   "TA_GetPKey",
+	procIsActivated:               "TA_IsActivated",
+	procIsDateValid:               "TA_IsDateValid",
+	procIsGenuine:                 "TA_IsGenuine",
+	procIsGenuineEx:               "TA_IsGenuineEx",
+	procGenuineDays:               "TA_GenuineDays",
+	procIsProductKeyValid:         "TA_IsProductKeyValid",
+	procSetCustomProxy:            "TA_SetCustomProxy",
+	procTrialDaysRemaining:        "TA_TrialDaysRemaining",
+	procUseTrial:                  "TA_UseTrial",
+	procUseTrialVerifiedRequest:   "TA_UseTrialVerifiedRequest",
+	procUseTrialVerifiedFromFile:  "TA_UseTrialVerifiedFromFile",
+	procExtendTrial:               "TA_ExtendTrial",
+	procSetCustomActDataPath:      "TA_SetCustomActDataPath",
+	procSetTrialCallback:          "TA_SetTrialCallback",
+	procBlackListKeys:             "TA_BlackListKeys",
+}
+
+// procCaller calls the TA_* functions of a TurboActivate library loaded at
+// runtime. Only how the functions are found and called is different for
+// dlopen (dynamic_unix.go) and LoadLibrary (dynamic_windows.go).
+// Implementations must be comparable, since the backend is a map key.
+type procCaller interface {
+	// call calls the function with the arguments (integers, or pointers
+	// to memory from alloc) and returns what it returned.
+	call(proc taProc, args ...uintptr) uintptr
+
+	// trialCallback returns the TrialChangeCallback that passes the trial
+	// callbacks on to nativeTrialCallback.
+	trialCallback() uintptr
+
+	// alloc returns size bytes of zeroed memory to pass to the library,
+	// which free frees once the call returned.
+	alloc(size uintptr) unsafe.Pointer
+	free(p unsafe.Pointer)
+}
+
+// procBackend is the Backend that calls into a TurboActivate library loaded
+// at runtime. It converts the arguments and return values of every
+// function, and procs calls them.
+type procBackend struct {
+	procs procCaller
+}
+
+// ACTIVATE_OPTIONS
+type taActivateOptions struct {
+	nLength    uint32
+	sExtraData uintptr
+}
+
+// GENUINE_OPTIONS
+type taGenuineOptions struct {
+	nLength             uint32
+	flags               uint32
+	nDaysBetweenChecks  uint32
+	nGraceDaysOnInetErr uint32
+}
+
+// taMem is the memory of the arguments of one call: the strings, buffers,
+// out parameters and option structs. It's allocated by procs.alloc, so with
+// cgo no Go memory (and so no Go pointer in a struct) is passed to C.
+type taMem struct {
+	procs procCaller
+	ptrs  []unsafe.Pointer
+}
+
+func (b procBackend) mem() *taMem {
+	return &taMem{procs: b.procs}
+}
+
+func (m *taMem) alloc(size uintptr) unsafe.Pointer {
+	var p = m.procs.alloc(size)
+	m.ptrs = append(m.ptrs, p)
+	return p
+}
+
+// free frees the memory once the call returned.
+func (m *taMem) free() {
+	for _, p := range m.ptrs {
+		m.procs.free(p)
+	}
+
+	m.ptrs = nil
+}
+
+// buf returns a pointer to a zeroed buffer of n characters, and the buffer.
+func (m *taMem) buf(n int) (uintptr, []taChar) {
+	var p = m.alloc(uintptr(n) * unsafe.Sizeof(taChar(0)))
+	return uintptr(p), unsafe.Slice((*taChar)(p), n)
+}
+
+// str copies s to a null terminated string and returns a pointer to it.
+func (m *taMem) str(s string) uintptr {
+	var native = taStr(s)
+
+	p, buf := m.buf(len(native))
+	copy(buf, native)
+
+	return p
+}
+
+// strOrNil is str, but an empty string is passed as nil.
+func (m *taMem) strOrNil(s string) uintptr {
+	if s == "" {
+		return 0
+	}
+
+	return m.str(s)
+}
+
+// outUint32 returns a pointer to a zeroed uint32 the library can set.
+func (m *taMem) outUint32() *uint32 {
+	return (*uint32)(m.alloc(unsafe.Sizeof(uint32(0))))
+}
+
+// activateOptions returns the ACTIVATE_OPTIONS with the extra data, or nil
+// if there isn't any.
+func (m *taMem) activateOptions(extraData string) uintptr {
+	if extraData == "" {
+		return 0
+	}
+
+	var actOptions = (*taActivateOptions)(m.alloc(unsafe.Sizeof(taActivateOptions{})))
+	actOptions.nLength = uint32(unsafe.Sizeof(*actOptions))
+	actOptions.sExtraData = m.str(extraData)
+	return uintptr(unsafe.Pointer(actOptions))
+}
+
+// call calls proc and returns its HRESULT.
+func (b procBackend) call(proc taProc, args ...uintptr) HRESULT {
+	return HRESULT(uint32(b.procs.call(proc, args...)))
+}
+
+func taBool(b bool) uintptr {
+	if b {
+		return 1
+	}
+
+	return 0
+}
+
+// taResult returns the string in buf if ret is TA_OK.
+func taResult(buf []taChar, ret HRESULT) (string, HRESULT) {
+
+	// TA_OK
+	if ret != 0x00 {
+		return "", ret
+	}
+
+	return taString(buf), ret
+}
+
+func (b procBackend) PDetsFromPath(filename string) HRESULT {
+
+	var m = b.mem()
+	defer m.free()
+
+	return b.call(procPDetsFromPath, m.str(filename))
+}
+
+func (b procBackend) PDetsFromByteArray(data []byte) HRESULT {
+
+	if len(data) == 0 {
+		// TA_E_INVALID_ARGS
+		return 0x13
+	}
+
+	var m = b.mem()
+	defer m.free()
+
+	var nativeData = m.alloc(uintptr(len(data)))
+	copy(unsafe.Slice((*byte)(nativeData), len(data)), data)
+
+	return b.call(procPDetsFromByteArray, uintptr(nativeData), uintptr(len(data)))
+}
+
+func (b procBackend) GetHandle(versionGUID string) uint32 {
+
+	var m = b.mem()
+	defer m.free()
+
+	return uint32(b.call(procGetHandle, m.str(versionGUID)))
+}
+
+func (b procBackend) GetVersion() (uint32, uint32, uint32, uint32, HRESULT) {
+
+	var m = b.mem()
+	defer m.free()
+
+	var major, minor, build, revision = m.outUint32(), m.outUint32(), m.outUint32(), m.outUint32()
+
+	var ret = b.call(procGetVersion, uintptr(unsafe.Pointer(major)), uintptr(unsafe.Pointer(minor)), uintptr(unsafe.Pointer(build)), uintptr(unsafe.Pointer(revision)))
+
+	return *major, *minor, *build, *revision, ret
+}
+
+func (b procBackend) Cleanup() HRESULT {
+
+	var ret = b.call(procCleanup)
+
+	// the handles (and so their callbacks) are gone
+	resetNativeTrialCallbacks(b)
+
+	return ret
+}
+
+func (b procBackend) Activate(handle uint32, extraData string) HRESULT {
+
+	var m = b.mem()
+	defer m.free()
+
+	return b.call(procActivate, uintptr(handle), m.activateOptions(extraData))
+}
+
+func (b procBackend) ActivationRequestToFile(handle uint32, filename string, extraData string) HRESULT {
+
+	var m = b.mem()
+	defer m.free()
+
+	return b.call(procActivationRequestToFile, uintptr(handle), m.str(filename), m.activateOptions(extraData))
+}
+
+func (b procBackend) ActivateFromFile(handle uint32, filename string) HRESULT {
+
+	var m = b.mem()
+	defer m.free()
+
+	return b.call(procActivateFromFile, uintptr(handle), m.str(filename))
+}
+
+func (b procBackend) CheckAndSavePKey(handle uint32, productKey string, flags TAFlags) HRESULT {
+
+	var m = b.mem()
+	defer m.free()
+
+	return b.call(procCheckAndSavePKey, uintptr(handle), m.str(productKey), uintptr(flags))
+}
+
+func (b procBackend) Deactivate(handle uint32, eraseProductKey bool) HRESULT {
+	return b.call(procDeactivate, uintptr(handle), taBool(eraseProductKey))
+}
+
+func (b procBackend) DeactivationRequestToFile(handle uint32, filename string, eraseProductKey bool) HRESULT {
+
+	var m = b.mem()
+	defer m.free()
+
+	return b.call(procDeactivationRequestToFile, uintptr(handle), m.str(filename), taBool(eraseProductKey))
+}
+
+func (b procBackend) GetExtraData(handle uint32) (string, HRESULT) {
+
+	var m = b.mem()
+	defer m.free()
+
+	// the first call returns the size of the buffer (including the null)
+	var size = b.call(procGetExtraData, uintptr(handle), 0, 0)
+
+	p, buf := m.buf(int(size) + 1)
+
+	var ret = b.call(procGetExtraData, uintptr(handle), p, uintptr(size))
+
+	return taResult(buf, ret)
+}
+
+func (b procBackend) GetFeatureValue(handle uint32, featureName string) (string, HRESULT) {
+
+	var m = b.mem()
+	defer m.free()
+
+	var nativeFeatureName = m.str(featureName)
+
+	// the first call returns the size of the buffer (including the null)
+	var size = b.call(procGetFeatureValue, uintptr(handle), nativeFeatureName, 0, 0)
+
+	p, buf := m.buf(int(size) + 1)
+
+	var ret = b.call(procGetFeatureValue, uintptr(handle), nativeFeatureName, p, uintptr(size))
+
+	return taResult(buf, ret)
+}
+
+func (b procBackend) GetPKey(handle uint32) (string, HRESULT) {
+
+	var m = b.mem()
+	defer m.free()
+
+	p, buf := m.buf(35)
+
+	var ret = b.call(procGetPKey, uintptr(handle), p, uintptr(len(buf)))
+
+	return taResult(buf, ret)
+}
+
+func (b procBackend) IsActivated(handle uint32) HRESULT {
+	return b.call(procIsActivated, uintptr(handle))
+}
+
+func (b procBackend) IsDateValid(handle uint32, dateTime string, flags TADateCheckFlags) HRESULT {
+
+	var m = b.mem()
+	defer m.free()
+
+	return b.call(procIsDateValid, uintptr(handle), m.str(dateTime), uintptr(flags))
+}
+
+func (b procBackend) IsGenuine(handle uint32) HRESULT {
+	return b.call(procIsGenuine, uintptr(handle))
+}
+
+func (b procBackend) IsGenuineEx(handle uint32, daysBetweenChecks uint32, graceDaysOnInetErr uint32, skipOffline bool, offlineShowInetErr bool) HRESULT {
+
+	var m = b.mem()
+	defer m.free()
+
+	var genOpts = (*taGenuineOptions)(m.alloc(unsafe.Sizeof(taGenuineOptions{})))
+
+	genOpts.nLength = uint32(unsafe.Sizeof(*genOpts))
+
+	if skipOffline {
+		// TA_SKIP_OFFLINE
+		genOpts.flags = 1
+
+		if offlineShowInetErr {
+			// TA_OFFLINE_SHOW_INET_ERR
+			genOpts.flags |= 2
+		}
+	}
+
+	genOpts.nDaysBetweenChecks = daysBetweenChecks
+	genOpts.nGraceDaysOnInetErr = graceDaysOnInetErr
+
+	return b.call(procIsGenuineEx, uintptr(handle), uintptr(unsafe.Pointer(genOpts)))
+}
+
+func (b procBackend) GenuineDays(handle uint32, daysBetweenChecks uint32, graceDaysOnInetErr uint32) (uint32, bool, HRESULT) {
+
+	var m = b.mem()
+	defer m.free()
+
+	var daysRemain = m.outUint32()
+	var inGrace = (*byte)(m.alloc(1))
+
+	var ret = b.call(procGenuineDays, uintptr(handle), uintptr(daysBetweenChecks), uintptr(graceDaysOnInetErr), uintptr(unsafe.Pointer(daysRemain)), uintptr(unsafe.Pointer(inGrace)))
+
+	return *daysRemain, *inGrace == 1, ret
+}
+
+func (b procBackend) IsProductKeyValid(handle uint32) HRESULT {
+	return b.call(procIsProductKeyValid, uintptr(handle))
+}
+
+func (b procBackend) SetCustomProxy(proxy string) HRESULT {
+
+	var m = b.mem()
+	defer m.free()
+
+	return b.call(procSetCustomProxy, m.str(proxy))
+}
+
+func (b procBackend) TrialDaysRemaining(handle uint32, flags TAFlags) (uint32, HRESULT) {
+
+	var m = b.mem()
+	defer m.free()
+
+	var daysRemain = m.outUint32()
+
+	var ret = b.call(procTrialDaysRemaining, uintptr(handle), uintptr(flags), uintptr(unsafe.Pointer(daysRemain)))
+
+	return *daysRemain, ret
+}
+
+func (b procBackend) UseTrial(handle uint32, flags TAFlags, extraData string) HRESULT {
+
+	var m = b.mem()
+	defer m.free()
+
+	return b.call(procUseTrial, uintptr(handle), uintptr(flags), m.strOrNil(extraData))
+}
+
+func (b procBackend) UseTrialVerifiedRequest(handle uint32, filename string, extraData string) HRESULT {
+
+	var m = b.mem()
+	defer m.free()
+
+	return b.call(procUseTrialVerifiedRequest, uintptr(handle), m.str(filename), m.strOrNil(extraData))
+}
+
+func (b procBackend) UseTrialVerifiedFromFile(handle uint32, filename string, flags TAFlags) HRESULT {
+
+	var m = b.mem()
+	defer m.free()
+
+	return b.call(procUseTrialVerifiedFromFile, uintptr(handle), m.str(filename), uintptr(flags))
+}
+
+func (b procBackend) ExtendTrial(handle uint32, flags TAFlags, trialExtension string) HRESULT {
+
+	var m = b.mem()
+	defer m.free()
+
+	return b.call(procExtendTrial, uintptr(handle), uintptr(flags), m.str(trialExtension))
+}
+
+func (b procBackend) SetCustomActDataPath(handle uint32, directory string) HRESULT {
+
+	var m = b.mem()
+	defer m.free()
+
+	return b.call(procSetCustomActDataPath, uintptr(handle), m.str(directory))
+}
+
+func (b procBackend) SetTrialCallback(handle uint32, callback func(status uint32)) HRESULT {
+
+	if callback == nil {
+		// the native callback stays set, but nativeTrialCallback ignores it
+		setNativeTrialCallback(b, handle, nil)
+		return 0x00
+	}
+
+	var slot = setNativeTrialCallback(b, handle, callback)
+
+	// the handle's slot is passed as the user defined pointer
+	var ret = b.call(procSetTrialCallback, uintptr(handle), b.procs.trialCallback(), slot)
+
+	if ret != 0x00 {
+		setNativeTrialCallback(b, handle, nil)
+	}
+
+	return ret
+}
+
+func (b procBackend) BlackListKeys(handle uint32, keys []string) HRESULT {
+
+	if len(keys) == 0 {
+		// TA_E_INVALID_ARGS
+		return 0x13
+	}
+
+	var m = b.mem()
+	defer m.free()
+
+	// the array of string pointers is in the call's memory too
+	var nativeKeys = m.alloc(uintptr(len(keys)) * unsafe.Sizeof(uintptr(0)))
+	var keyPtrs = unsafe.Slice((*uintptr)(nativeKeys), len(keys))
+
+	for i, key := range keys {
+		keyPtrs[i] = m.str(key)
+	}
+
+	return b.call(procBlackListKeys, uintptr(handle), uintptr(nativeKeys), uintptr(len(keys)))
+}
@@ -0,0 +1,91 @@
+// Copyright 2018 wyDay, LLC. All rights reserved.
+
+// +build cgo,!windows
+
+package turboactivate // import "golang.wyday.com/turboactivate"
+
+/*
+#include <stdint.h>
+#include <stdlib.h>
+
+// taTrialCallbackGo is exported from native_callback.go.
+extern void taTrialCallbackGo(uint32_t status, uintptr_t slot);
+
+// ta_trial_callback passes the trial callbacks on to Go. The user
+// defined pointer is the slot of the handle the callback was set for.
+static void ta_trial_callback(uint32_t status, void * userDefinedPtr)
+{
+	taTrialCallbackGo(status, (uintptr_t)userDefinedPtr);
+}
+
+static uintptr_t ta_trial_callback_ptr(void)
+{
+	return (uintptr_t)ta_trial_callback;
+}
+
+// Go can't call C function pointers, so every TA_* function loaded with
+// dlopen is called through ta_call. They take at most 5 arguments, all
+// integers or pointers, and the extra arguments are ignored, since the
+// caller cleans them up.
+typedef uintptr_t (* ta_proc)(uintptr_t, uintptr_t, uintptr_t, uintptr_t, uintptr_t);
+
+static uintptr_t ta_call(void * proc, uintptr_t a0, uintptr_t a1, uintptr_t a2, uintptr_t a3, uintptr_t a4)
+{
+	return ((ta_proc)proc)(a0, a1, a2, a3, a4);
+}
+*/
+import "C"
+import (
+	"unsafe"
+)
+
+// cgoProcs calls the TA_* functions through their C function pointers. The
+// pointers are in C memory, since they're passed to C on every call.
+type cgoProcs struct {
+	fns *[numProcs]unsafe.Pointer
+}
+
+// newCgoProcs looks up every TA_* function with resolve. If resolve returns
+// nil for one, it returns the name of that function instead.
+func newCgoProcs(resolve func(name *C.char) unsafe.Pointer) (cgoProcs, string) {
+
+	var fns = (*[numProcs]unsafe.Pointer)(C.calloc(C.size_t(numProcs), C.size_t(unsafe.Sizeof(unsafe.Pointer(nil)))))
+
+	for proc, name := range procNames {
+		var nativeName = C.CString(name)
+
+		fns[proc] = resolve(nativeName)
+
+		C.free(unsafe.Pointer(nativeName))
+
+		if fns[proc] == nil {
+			C.free(unsafe.Pointer(fns))
+			return cgoProcs{}, name
+		}
+	}
+
+	return cgoProcs{fns}, ""
+}
+
+func (p cgoProcs) call(proc taProc, args ...uintptr) uintptr {
+
+	var a [5]C.uintptr_t
+
+	for i, arg := range args {
+		a[i] = C.uintptr_t(arg)
+	}
+
+	return uintptr(C.ta_call(p.fns[proc], a[0], a[1], a[2], a[3], a[4]))
+}
+
+func (cgoProcs) trialCallback() uintptr {
+	return uintptr(C.ta_trial_callback_ptr())
+}
+
+func (cgoProcs) alloc(size uintptr) unsafe.Pointer {
+	return C.calloc(1, C.size_t(size))
+}
+
+func (cgoProcs) free(p unsafe.Pointer) {
+	C.free(p)
+}
@@ -0,0 +1,37 @@
+// Copyright 2018 wyDay, LLC. All rights reserved.
+
+// +build cgo,!windows
+
+package turboactivate // import "golang.wyday.com/turboactivate"
+
+import (
+	"bytes"
+	"strings"
+)
+
+// taChar is the character type of the library's strings: UTF-8 on Unix.
+type taChar = byte
+
+// taStr converts s to a null terminated string for the library. Strings
+// are cut at a NUL, like the library would.
+func taStr(s string) []taChar {
+
+	if i := strings.IndexByte(s, 0); i != -1 {
+		s = s[:i]
+	}
+
+	var buf = make([]taChar, len(s)+1)
+	copy(buf, s)
+
+	return buf
+}
+
+// taString converts the null terminated string in buf to a Go string.
+func taString(buf []taChar) string {
+
+	if i := bytes.IndexByte(buf, 0); i != -1 {
+		buf = buf[:i]
+	}
+
+	return string(buf)
+}
@@ -0,0 +1,31 @@
+// Copyright 2018 wyDay, LLC. All rights reserved.
+
+// +build windows
+
+package turboactivate // import "golang.wyday.com/turboactivate"
+
+import (
+	"strings"
+	"syscall"
+)
+
+// taChar is the character type of the library's strings: UTF-16 on Windows.
+type taChar = uint16
+
+// taStr converts s to a null terminated UTF-16 string for the library.
+// Strings are cut at a NUL, like the library would.
+func taStr(s string) []taChar {
+
+	if i := strings.IndexByte(s, 0); i != -1 {
+		s = s[:i]
+	}
+
+	buf, _ := syscall.UTF16FromString(s)
+
+	return buf
+}
+
+// taString converts the null terminated string in buf to a Go string.
+func taString(buf []taChar) string {
+	return syscall.UTF16ToString(buf)
+}
@@ -0,0 +1,328 @@
+// Copyright 2018 wyDay, LLC. All rights reserved.
+
+// Package qr moves offline activation, deactivation and verified trial
+// requests and responses between an air-gapped computer and one connected
+// to the internet as QR codes.
+//
+// The payload is split into one or more parts that each fit in a QR code
+// that's easy to scan. Each part starts with a "TA1:<id>:<i>/<n>:" header,
+// where id identifies the payload (so the parts of different payloads can't
+// be mixed up), i is the part number (counting from 1) and n is the number
+// of parts. The rest is the base64 encoded slice of the payload.
+//
+// On the air-gapped computer:
+//
+//	codes, err := qr.ActivationRequest(ta, "", qr.Options{})
+//	for _, code := range codes {
+//		fmt.Println(code.ASCII())
+//	}
+//
+// And once the response was scanned back in:
+//
+//	var a qr.Assembler
+//	for _, part := range scannedParts {
+//		if _, err := a.Add(part); err != nil { ... }
+//	}
+//	err := qr.ActivateFrom(ta, &a)
+package qr // import "golang.wyday.com/turboactivate/qr"
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+
+	qrcode "github.com/skip2/go-qrcode"
+
+	"golang.wyday.com/turboactivate"
+)
+
+// prefix is the start of the header of every part.
+const prefix = "TA1:"
+
+// DefaultChunkSize is the default maximum number of payload bytes per QR code.
+// It keeps the codes small enough to be scanned from a screen by a phone.
+const DefaultChunkSize = 600
+
+// MaxParts is the maximum number of parts of a payload. The offline requests
+// and responses only need a few, and it keeps a bad scan from making the
+// Assembler allocate a huge number of parts.
+const MaxParts = 256
+
+// Options configures how payloads are split into QR codes.
+// The zero value uses the defaults.
+type Options struct {
+	// ChunkSize is the maximum number of payload bytes per QR code.
+	// Defaults to DefaultChunkSize.
+	ChunkSize int
+
+	// Level is the error recovery level of the QR codes. The zero value is
+	// qrcode.Low, which fits the most data in each code.
+	Level qrcode.RecoveryLevel
+}
+
+var (
+	// ErrInvalidPart is returned when a scanned part isn't in the "TA1:" format.
+	ErrInvalidPart = errors.New("qr: not a TurboActivate QR code part")
+
+	// ErrMismatchedPart is returned when a scanned part belongs to a different
+	// payload than the parts added before.
+	ErrMismatchedPart = errors.New("qr: part belongs to a different payload")
+
+	// ErrIncomplete is returned when the payload is read before all of its parts were added.
+	ErrIncomplete = errors.New("qr: not all parts were added")
+
+	// ErrChecksum is returned when the reassembled payload doesn't match its id.
+	ErrChecksum = errors.New("qr: the reassembled payload is corrupted")
+
+	// ErrTooManyParts is returned when a payload has more than MaxParts parts.
+	ErrTooManyParts = errors.New("qr: the payload has too many parts")
+)
+
+// Code is one QR code of a payload.
+type Code struct {
+	// Index is the part number of the code (counting from 1).
+	Index int
+
+	// Total is the number of parts of the payload.
+	Total int
+
+	// Content is the text encoded in the QR code.
+	Content string
+
+	level qrcode.RecoveryLevel
+}
+
+// PNG encodes the QR code as a size x size pixel PNG image.
+func (c Code) PNG(size int) ([]byte, error) {
+	q, err := qrcode.New(c.Content, c.level)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return q.PNG(size)
+}
+
+// ASCII returns the QR code drawn with block characters, to be shown in a terminal.
+func (c Code) ASCII() string {
+	q, err := qrcode.New(c.Content, c.level)
+
+	if err != nil {
+		return ""
+	}
+
+	return q.ToSmallString(false)
+}
+
+// payloadID returns the id of the payload used in the part headers.
+func payloadID(payload []byte) string {
+	var sum = sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:4])
+}
+
+// Encode splits the payload into parts and returns their QR codes.
+func Encode(payload []byte, opts Options) ([]Code, error) {
+	if opts.ChunkSize <= 0 {
+		opts.ChunkSize = DefaultChunkSize
+	}
+
+	var id = payloadID(payload)
+	var total = (len(payload) + opts.ChunkSize - 1) / opts.ChunkSize
+
+	if total == 0 {
+		total = 1
+	}
+
+	if total > MaxParts {
+		return nil, ErrTooManyParts
+	}
+
+	var codes = make([]Code, 0, total)
+
+	for i := 0; i < total; i++ {
+		var end = (i + 1) * opts.ChunkSize
+
+		if end > len(payload) {
+			end = len(payload)
+		}
+
+		var content = prefix + id + ":" + strconv.Itoa(i+1) + "/" + strconv.Itoa(total) + ":" +
+			base64.StdEncoding.EncodeToString(payload[i*opts.ChunkSize:end])
+
+		// make sure the content fits in a QR code
+		if _, err := qrcode.New(content, opts.Level); err != nil {
+			return nil, err
+		}
+
+		codes = append(codes, Code{Index: i + 1, Total: total, Content: content, level: opts.Level})
+	}
+
+	return codes, nil
+}
+
+// ActivationRequest gets the offline activation request and encodes it as QR codes.
+func ActivationRequest(ta *turboactivate.TurboActivate, extraData string, opts Options) ([]Code, error) {
+	var buf bytes.Buffer
+
+	if err := ta.ActivationRequest(&buf, extraData); err != nil {
+		return nil, err
+	}
+
+	return Encode(buf.Bytes(), opts)
+}
+
+// DeactivationRequest deactivates the product and encodes the offline
+// deactivation request as QR codes.
+func DeactivationRequest(ta *turboactivate.TurboActivate, eraseProductKey bool, opts Options) ([]Code, error) {
+	var buf bytes.Buffer
+
+	if err := ta.DeactivationRequest(&buf, eraseProductKey); err != nil {
+		return nil, err
+	}
+
+	return Encode(buf.Bytes(), opts)
+}
+
+// UseTrialVerifiedRequest gets the offline verified trial request and encodes it as QR codes.
+func UseTrialVerifiedRequest(ta *turboactivate.TurboActivate, extraData string, opts Options) ([]Code, error) {
+	var buf bytes.Buffer
+
+	if err := ta.UseTrialVerifiedRequestTo(&buf, extraData); err != nil {
+		return nil, err
+	}
+
+	return Encode(buf.Bytes(), opts)
+}
+
+// Assembler reassembles a payload from its scanned parts, which can be added
+// in any order and more than once. The zero value is ready to use.
+type Assembler struct {
+	id    string
+	parts []string
+	added []bool
+}
+
+// Add adds a scanned part. Returns true once all the parts were added.
+func (a *Assembler) Add(part string) (bool, error) {
+	if !strings.HasPrefix(part, prefix) {
+		return false, ErrInvalidPart
+	}
+
+	var fields = strings.SplitN(part[len(prefix):], ":", 3)
+
+	if len(fields) != 3 {
+		return false, ErrInvalidPart
+	}
+
+	var nums = strings.SplitN(fields[1], "/", 2)
+
+	if len(nums) != 2 {
+		return false, ErrInvalidPart
+	}
+
+	index, err := strconv.Atoi(nums[0])
+
+	if err != nil {
+		return false, ErrInvalidPart
+	}
+
+	total, err := strconv.Atoi(nums[1])
+
+	if err != nil || total < 1 || index < 1 || index > total {
+		return false, ErrInvalidPart
+	}
+
+	if total > MaxParts {
+		return false, ErrTooManyParts
+	}
+
+	if a.parts == nil {
+		a.id = fields[0]
+		a.parts = make([]string, total)
+		a.added = make([]bool, total)
+	} else if a.id != fields[0] || len(a.parts) != total {
+		return false, ErrMismatchedPart
+	}
+
+	a.parts[index-1] = fields[2]
+	a.added[index-1] = true
+
+	return a.Complete(), nil
+}
+
+// Complete returns whether all the parts were added.
+func (a *Assembler) Complete() bool {
+	return a.parts != nil && len(a.Missing()) == 0
+}
+
+// Missing returns the numbers of the parts that weren't added yet.
+func (a *Assembler) Missing() []int {
+	var missing []int
+
+	for i, added := range a.added {
+		if !added {
+			missing = append(missing, i+1)
+		}
+	}
+
+	return missing
+}
+
+// Reset discards the added parts, so another payload can be assembled.
+func (a *Assembler) Reset() {
+	a.id = ""
+	a.parts = nil
+	a.added = nil
+}
+
+// Bytes returns the reassembled payload.
+func (a *Assembler) Bytes() ([]byte, error) {
+	if !a.Complete() {
+		return nil, ErrIncomplete
+	}
+
+	var payload []byte
+
+	for _, p := range a.parts {
+		chunk, err := base64.StdEncoding.DecodeString(p)
+
+		if err != nil {
+			return nil, ErrInvalidPart
+		}
+
+		payload = append(payload, chunk...)
+	}
+
+	if payloadID(payload) != a.id {
+		return nil, ErrChecksum
+	}
+
+	return payload, nil
+}
+
+// ActivateFrom activates from the offline activation response reassembled by a.
+func ActivateFrom(ta *turboactivate.TurboActivate, a *Assembler) error {
+	payload, err := a.Bytes()
+
+	if err != nil {
+		return err
+	}
+
+	return ta.ActivateFrom(bytes.NewReader(payload))
+}
+
+// UseTrialVerifiedFrom starts the verified trial from the offline verified
+// trial response reassembled by a.
+func UseTrialVerifiedFrom(ta *turboactivate.TurboActivate, a *Assembler, flags turboactivate.TAFlags) error {
+	payload, err := a.Bytes()
+
+	if err != nil {
+		return err
+	}
+
+	return ta.UseTrialVerifiedFrom(bytes.NewReader(payload), flags)
+}
@@ -0,0 +1,169 @@
+// Copyright 2018 wyDay, LLC. All rights reserved.
+
+package qr_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"golang.wyday.com/turboactivate"
+	"golang.wyday.com/turboactivate/fake"
+	"golang.wyday.com/turboactivate/qr"
+)
+
+func TestRoundTrip(t *testing.T) {
+	var payload = bytes.Repeat([]byte("offline activation request "), 100)
+
+	codes, err := qr.Encode(payload, qr.Options{ChunkSize: 500})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(codes) != 6 {
+		t.Fatalf("%d codes; want 6", len(codes))
+	}
+
+	var a qr.Assembler
+
+	// in reverse order, with a part scanned twice
+	for i := len(codes) - 1; i >= 0; i-- {
+		done, err := a.Add(codes[i].Content)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if done != (i == 0) {
+			t.Errorf("Add(part %d) = %v", codes[i].Index, done)
+		}
+
+		if i == 3 {
+			a.Add(codes[i].Content)
+		}
+	}
+
+	got, err := a.Bytes()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(got, payload) {
+		t.Error("the reassembled payload doesn't match")
+	}
+
+	if png, err := codes[0].PNG(256); err != nil || len(png) == 0 {
+		t.Errorf("PNG() = %d bytes, %v", len(png), err)
+	}
+}
+
+func TestAssemblerErrors(t *testing.T) {
+	codes, err := qr.Encode([]byte("some payload that's split in parts"), qr.Options{ChunkSize: 10})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	other, err := qr.Encode([]byte("another payload"), qr.Options{ChunkSize: 10})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var a qr.Assembler
+
+	for _, part := range []string{"", "hello", "TA1:x", "TA1:x:1:", "TA1:x:0/2:", "TA1:x:3/2:", "TA1:x:a/2:"} {
+		if _, err = a.Add(part); !errors.Is(err, qr.ErrInvalidPart) {
+			t.Errorf("Add(%q) = %v; want ErrInvalidPart", part, err)
+		}
+	}
+
+	// a bad scan can't make the assembler allocate billions of parts
+	if _, err = a.Add("TA1:x:1/2000000000:"); !errors.Is(err, qr.ErrTooManyParts) {
+		t.Errorf("Add(huge total) = %v; want ErrTooManyParts", err)
+	}
+
+	if _, err = a.Add(codes[0].Content); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = a.Add(other[1].Content); !errors.Is(err, qr.ErrMismatchedPart) {
+		t.Errorf("Add(other payload) = %v; want ErrMismatchedPart", err)
+	}
+
+	if _, err = a.Bytes(); !errors.Is(err, qr.ErrIncomplete) {
+		t.Errorf("Bytes() = %v; want ErrIncomplete", err)
+	}
+
+	if missing := a.Missing(); len(missing) != len(codes)-1 || missing[0] != 2 {
+		t.Errorf("Missing() = %v", missing)
+	}
+
+	// corrupt the last part
+	for _, c := range codes[1 : len(codes)-1] {
+		a.Add(c.Content)
+	}
+
+	var last = codes[len(codes)-1].Content
+	var i = strings.LastIndex(last, ":")
+	a.Add(last[:i+1] + "AAAA")
+
+	if _, err = a.Bytes(); !errors.Is(err, qr.ErrChecksum) {
+		t.Errorf("Bytes() = %v; want ErrChecksum", err)
+	}
+
+	a.Reset()
+
+	if a.Complete() {
+		t.Error("Complete() after Reset()")
+	}
+
+	if _, err = qr.Encode(make([]byte, qr.MaxParts*10+1), qr.Options{ChunkSize: 10}); !errors.Is(err, qr.ErrTooManyParts) {
+		t.Errorf("Encode(too big) = %v; want ErrTooManyParts", err)
+	}
+}
+
+func TestOfflineActivation(t *testing.T) {
+	const guid = "18324776654b3946fc44a5f3.49025204"
+	const pkey = "AAAA-BBBB-CCCC-DDDD-EEEE-FFFF-GGGG"
+
+	b := fake.New()
+	b.AddKey(guid, pkey, fake.Key{})
+	b.SetOnline(false)
+
+	ta, err := turboactivate.New(guid, turboactivate.WithBackend(b))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer ta.Close()
+
+	if _, err = ta.CheckAndSavePKey(pkey, turboactivate.TAUser); err != nil {
+		t.Fatal(err)
+	}
+
+	codes, err := qr.ActivationRequest(ta, "", qr.Options{ChunkSize: 50})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the fake accepts the request as the response
+	var a qr.Assembler
+
+	for _, c := range codes {
+		a.Add(c.Content)
+	}
+
+	if err = qr.ActivateFrom(ta, &a); err != nil {
+		t.Fatal(err)
+	}
+
+	if ok, err := ta.IsActivated(); !ok || err != nil {
+		t.Errorf("IsActivated() = %v, %v; want true", ok, err)
+	}
+}
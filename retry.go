@@ -0,0 +1,145 @@
+// Copyright 2018 wyDay, LLC. All rights reserved.
+
+package turboactivate // import "golang.wyday.com/turboactivate"
+
+import (
+	"math/rand"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy configures how the functions that contact the LimeLM servers
+// (Activate, Deactivate, IsGenuine, IsGenuineEx, UseTrial, ExtendTrial and
+// their Context variants) retry transient failures. Set it with WithRetryPolicy.
+//
+// The delay before the n-th retry is BaseDelay * 2^(n-1), capped at MaxDelay,
+// minus a random part of up to Jitter * delay. The Context variants stop
+// retrying as soon as the context is done.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of calls, including the first one.
+	// 0 or 1 means the calls aren't retried.
+	MaxAttempts int
+
+	// BaseDelay is the delay before the first retry. Defaults to 1 second.
+	BaseDelay time.Duration
+
+	// MaxDelay is the maximum delay between retries. Defaults to 30 seconds.
+	MaxDelay time.Duration
+
+	// Jitter is the fraction (0 to 1) of each delay that's randomized.
+	Jitter float64
+
+	// Retryable are the HRESULTs that are retried. Defaults to TAEInet,
+	// TAEInetTimeout and TAEInetTLS.
+	Retryable []HRESULT
+}
+
+// DefaultRetryPolicy returns a RetryPolicy that makes up to 4 attempts
+// with 1, 2 and 4 second delays (with 20% jitter) on internet errors.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 4,
+		BaseDelay:   time.Second,
+		MaxDelay:    30 * time.Second,
+		Jitter:      0.2,
+		Retryable:   []HRESULT{TAEInet, TAEInetTimeout, TAEInetTLS},
+	}
+}
+
+// WithRetryPolicy makes the functions that contact the LimeLM servers retry
+// their transient failures according to p.
+//
+// The failures that are still retryable when the attempts run out are
+// returned as a *RetryError with the number of attempts. The exception is
+// TA_E_INET from IsGenuine and IsGenuineEx (and their Context variants):
+// they return IGRInternetError with a nil error, as they do without retries,
+// so the number of attempts isn't reported.
+func WithRetryPolicy(p RetryPolicy) Option {
+	return func(c *config) error {
+		if p.BaseDelay <= 0 {
+			p.BaseDelay = time.Second
+		}
+
+		if p.MaxDelay <= 0 {
+			p.MaxDelay = 30 * time.Second
+		}
+
+		if p.Jitter < 0 {
+			p.Jitter = 0
+		} else if p.Jitter > 1 {
+			p.Jitter = 1
+		}
+
+		if p.Retryable == nil {
+			p.Retryable = DefaultRetryPolicy().Retryable
+		}
+
+		c.retry = &p
+		return nil
+	}
+}
+
+// shouldRetry returns whether the call that returned ret on the attempt
+// (counting from 1) should be made again. A nil policy never retries.
+func (p *RetryPolicy) shouldRetry(ret HRESULT, attempt int) bool {
+	if p == nil || attempt >= p.MaxAttempts {
+		return false
+	}
+
+	for _, r := range p.Retryable {
+		if r == ret {
+			return true
+		}
+	}
+
+	return false
+}
+
+// delay returns how long to wait after the attempt (counting from 1).
+func (p *RetryPolicy) delay(attempt int) time.Duration {
+	var d = p.BaseDelay
+
+	for i := 1; i < attempt && d < p.MaxDelay; i++ {
+		d *= 2
+	}
+
+	if d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+
+	if p.Jitter > 0 {
+		d -= time.Duration(rand.Float64() * p.Jitter * float64(d))
+	}
+
+	return d
+}
+
+// RetryError is returned when a call still failed after being retried.
+// It wraps the error of the last attempt, so errors.Is and errors.As
+// work as they do without retries.
+type RetryError struct {
+	// Attempts is the number of calls made.
+	Attempts int
+
+	// Err is the error of the last attempt.
+	Err error
+}
+
+// Error returns the description of the last error and the attempt count.
+func (e *RetryError) Error() string {
+	return e.Err.Error() + " (after " + strconv.Itoa(e.Attempts) + " attempts)"
+}
+
+// Unwrap returns the error of the last attempt.
+func (e *RetryError) Unwrap() error {
+	return e.Err
+}
+
+// retryErr wraps err in a RetryError if more than one attempt was made.
+func retryErr(err error, attempts int) error {
+	if attempts <= 1 {
+		return err
+	}
+
+	return &RetryError{Attempts: attempts, Err: err}
+}
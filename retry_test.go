@@ -0,0 +1,111 @@
+// Copyright 2018 wyDay, LLC. All rights reserved.
+
+package turboactivate_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"golang.wyday.com/turboactivate"
+	"golang.wyday.com/turboactivate/fake"
+	"golang.wyday.com/turboactivate/internal/tatest"
+)
+
+func TestRetry(t *testing.T) {
+	b := fake.New()
+	ta := tatest.New(t, b, tatest.WithKey(nil), tatest.WithOptions(turboactivate.WithRetryPolicy(turboactivate.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond})))
+
+	// a transient failure is retried
+	b.FailNext("Activate", turboactivate.TAEInet)
+
+	if err := ta.Activate(""); err != nil {
+		t.Fatalf("Activate() = %v", err)
+	}
+
+	if n := b.Calls("Activate"); n != 2 {
+		t.Errorf("Activate was called %d times; want 2", n)
+	}
+
+	// until the attempts run out
+	b.Fail("IsGenuineEx", turboactivate.TAEInetTimeout)
+
+	_, err := ta.IsGenuineEx(90, 14, false, false)
+
+	var retryErr *turboactivate.RetryError
+
+	if !errors.As(err, &retryErr) || retryErr.Attempts != 3 {
+		t.Errorf("IsGenuineEx() = %v; want a RetryError after 3 attempts", err)
+	}
+
+	if n := b.Calls("IsGenuineEx"); n != 3 {
+		t.Errorf("IsGenuineEx was called %d times; want 3", n)
+	}
+
+	b.ClearFailures()
+
+	// the other failures aren't
+	b.FailNext("Deactivate", turboactivate.TAEInvalidFlags)
+
+	err = ta.Deactivate(false)
+
+	if errors.As(err, &retryErr) || !errors.Is(err, turboactivate.ErrInvalidFlags) {
+		t.Errorf("Deactivate() = %v; want ErrInvalidFlags without retrying", err)
+	}
+
+	if n := b.Calls("Deactivate"); n != 1 {
+		t.Errorf("Deactivate was called %d times; want 1", n)
+	}
+}
+
+func TestRetryDefaults(t *testing.T) {
+	b := fake.New()
+	ta := tatest.New(t, b, tatest.WithKey(nil), tatest.WithOptions(turboactivate.WithRetryPolicy(turboactivate.RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond, Jitter: 5})))
+
+	// the Retryable HRESULTs default to the internet errors (and the Jitter
+	// is at most 1)
+	b.Fail("Activate", turboactivate.TAEInetTLS)
+
+	if err := ta.Activate(""); !errors.Is(err, turboactivate.ErrInetTLS) {
+		t.Errorf("Activate() = %v; want ErrInetTLS", err)
+	}
+
+	if n := b.Calls("Activate"); n != 2 {
+		t.Errorf("Activate was called %d times; want 2", n)
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	b := fake.New()
+	ta := tatest.New(t, b, tatest.WithKey(nil), tatest.WithOptions(turboactivate.WithRetryPolicy(turboactivate.RetryPolicy{MaxAttempts: 4, BaseDelay: 10 * time.Millisecond, MaxDelay: 15 * time.Millisecond})))
+
+	b.Fail("Activate", turboactivate.TAEInet)
+
+	var start = time.Now()
+
+	ta.Activate("")
+
+	// 10ms, then 20ms and 40ms capped at 15ms
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Errorf("the retries took %v; want at least 40ms", elapsed)
+	}
+}
+
+func TestRetryStopsWhenContextIsDone(t *testing.T) {
+	b := fake.New()
+	ta := tatest.New(t, b, tatest.WithKey(nil), tatest.WithOptions(turboactivate.WithRetryPolicy(turboactivate.RetryPolicy{MaxAttempts: 4, BaseDelay: time.Hour})))
+
+	b.Fail("Activate", turboactivate.TAEInet)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if err := ta.ActivateContext(ctx, ""); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("ActivateContext() = %v; want DeadlineExceeded", err)
+	}
+
+	if n := b.Calls("Activate"); n != 1 {
+		t.Errorf("Activate was called %d times; want 1", n)
+	}
+}
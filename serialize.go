@@ -0,0 +1,365 @@
+// Copyright 2018 wyDay, LLC. All rights reserved.
+
+package turboactivate // import "golang.wyday.com/turboactivate"
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	// globalMu serializes the library functions that aren't tied to a
+	// handle (loading the product details, getting handles, the proxy).
+	globalMu sync.Mutex
+
+	// handleMus has the mutex that serializes the calls for each handle of
+	// each backend. The mutex is removed when the last instance using the
+	// handle is closed (see forgetHandleLock).
+	handleMusMu sync.Mutex
+	handleMus   = map[handleKey]*handleMutex{}
+)
+
+// handleMutex is the mutex of a handle. users counts the calls holding it or
+// waiting for it, so it's only removed from handleMus when it's unused.
+type handleMutex struct {
+	sync.Mutex
+	users atomic.Int32
+}
+
+// Unlock unlocks the mutex and removes the call from its users.
+func (m *handleMutex) Unlock() {
+	m.Mutex.Unlock()
+	m.users.Add(-1)
+}
+
+// handleKey identifies a handle of a backend. Different backends (e.g. two
+// fake backends) can return the same handle for different products.
+type handleKey struct {
+	backend Backend
+	handle  uint32
+}
+
+// backendKey returns b if it can be used as a map key. Backends that can't
+// be compared (e.g. a struct with a map field) return nil, so their handles
+// share the mutexes of the other backends that can't be compared. That's
+// slower, but still safe.
+func backendKey(b Backend) (key Backend) {
+	defer func() {
+		if recover() != nil {
+			key = nil
+		}
+	}()
+
+	_ = map[Backend]bool{b: true}
+	return b
+}
+
+// backendOf returns the backend that hands out the handles of b (as a map
+// key). The handles of a serializedBackend belong to the backend it was
+// created for.
+func backendOf(b Backend) Backend {
+	if s, ok := b.(serializedBackend); ok {
+		return s.id
+	}
+
+	return backendKey(b)
+}
+
+// keyOf returns the key of the handle of the backend.
+func keyOf(b Backend, handle uint32) handleKey {
+	return handleKey{backend: backendOf(b), handle: handle}
+}
+
+// handleLock gets the mutex for the handle. The caller must lock it and then
+// unlock it.
+func handleLock(key handleKey) *handleMutex {
+	handleMusMu.Lock()
+	defer handleMusMu.Unlock()
+
+	mu, ok := handleMus[key]
+
+	if !ok {
+		mu = &handleMutex{}
+		handleMus[key] = mu
+	}
+
+	mu.users.Add(1)
+	return mu
+}
+
+// forgetHandleLock removes the mutex of the handle, unless a call is still
+// using it (the handles are reused, so the next call creates it again).
+func forgetHandleLock(key handleKey) {
+	handleMusMu.Lock()
+	defer handleMusMu.Unlock()
+
+	if mu, ok := handleMus[key]; ok && mu.users.Load() == 0 {
+		delete(handleMus, key)
+	}
+}
+
+// serializedBackend wraps a Backend so that only one call per handle runs at
+// a time, no matter how many goroutines (or TurboActivate instances for the
+// same VersionGUID) use it. This is what makes TurboActivate safe for
+// concurrent use.
+type serializedBackend struct {
+	b Backend
+
+	// id is the backend that hands out the handles (that is, b before the
+	// logging and the WithBackendWrapper wrappers), so every instance using
+	// it shares the same mutexes.
+	id Backend
+}
+
+// serialize wraps the backend in a serializedBackend (once).
+func serialize(b Backend) Backend {
+	return serializeAs(b, b)
+}
+
+// serializeAs wraps the backend in a serializedBackend (once) that serializes
+// the calls by the handles of id.
+func serializeAs(b Backend, id Backend) Backend {
+	if _, ok := b.(serializedBackend); ok {
+		return b
+	}
+
+	return serializedBackend{b: b, id: backendKey(id)}
+}
+
+func (s serializedBackend) PDetsFromPath(filename string) HRESULT {
+	globalMu.Lock()
+	defer globalMu.Unlock()
+
+	return s.b.PDetsFromPath(filename)
+}
+
+func (s serializedBackend) PDetsFromByteArray(data []byte) HRESULT {
+	globalMu.Lock()
+	defer globalMu.Unlock()
+
+	return s.b.PDetsFromByteArray(data)
+}
+
+func (s serializedBackend) GetHandle(versionGUID string) uint32 {
+	globalMu.Lock()
+	defer globalMu.Unlock()
+
+	return s.b.GetHandle(versionGUID)
+}
+
+func (s serializedBackend) SetCustomProxy(proxy string) HRESULT {
+	globalMu.Lock()
+	defer globalMu.Unlock()
+
+	return s.b.SetCustomProxy(proxy)
+}
+
+func (s serializedBackend) GetVersion() (uint32, uint32, uint32, uint32, HRESULT) {
+	globalMu.Lock()
+	defer globalMu.Unlock()
+
+	return s.b.GetVersion()
+}
+
+// Cleanup waits for the calls of every handle to finish, since it invalidates them.
+func (s serializedBackend) Cleanup() HRESULT {
+	globalMu.Lock()
+	defer globalMu.Unlock()
+
+	handleMusMu.Lock()
+	defer handleMusMu.Unlock()
+
+	for key, mu := range handleMus {
+		if key.backend == s.id {
+			mu.users.Add(1)
+			mu.Lock()
+			defer mu.Unlock()
+		}
+	}
+
+	return s.b.Cleanup()
+}
+
+func (s serializedBackend) Activate(handle uint32, extraData string) HRESULT {
+	mu := handleLock(handleKey{s.id, handle})
+	mu.Lock()
+	defer mu.Unlock()
+
+	return s.b.Activate(handle, extraData)
+}
+
+func (s serializedBackend) ActivationRequestToFile(handle uint32, filename string, extraData string) HRESULT {
+	mu := handleLock(handleKey{s.id, handle})
+	mu.Lock()
+	defer mu.Unlock()
+
+	return s.b.ActivationRequestToFile(handle, filename, extraData)
+}
+
+func (s serializedBackend) ActivateFromFile(handle uint32, filename string) HRESULT {
+	mu := handleLock(handleKey{s.id, handle})
+	mu.Lock()
+	defer mu.Unlock()
+
+	return s.b.ActivateFromFile(handle, filename)
+}
+
+func (s serializedBackend) CheckAndSavePKey(handle uint32, productKey string, flags TAFlags) HRESULT {
+	mu := handleLock(handleKey{s.id, handle})
+	mu.Lock()
+	defer mu.Unlock()
+
+	return s.b.CheckAndSavePKey(handle, productKey, flags)
+}
+
+func (s serializedBackend) Deactivate(handle uint32, eraseProductKey bool) HRESULT {
+	mu := handleLock(handleKey{s.id, handle})
+	mu.Lock()
+	defer mu.Unlock()
+
+	return s.b.Deactivate(handle, eraseProductKey)
+}
+
+func (s serializedBackend) DeactivationRequestToFile(handle uint32, filename string, eraseProductKey bool) HRESULT {
+	mu := handleLock(handleKey{s.id, handle})
+	mu.Lock()
+	defer mu.Unlock()
+
+	return s.b.DeactivationRequestToFile(handle, filename, eraseProductKey)
+}
+
+func (s serializedBackend) GetExtraData(handle uint32) (string, HRESULT) {
+	mu := handleLock(handleKey{s.id, handle})
+	mu.Lock()
+	defer mu.Unlock()
+
+	return s.b.GetExtraData(handle)
+}
+
+func (s serializedBackend) GetFeatureValue(handle uint32, featureName string) (string, HRESULT) {
+	mu := handleLock(handleKey{s.id, handle})
+	mu.Lock()
+	defer mu.Unlock()
+
+	return s.b.GetFeatureValue(handle, featureName)
+}
+
+func (s serializedBackend) GetPKey(handle uint32) (string, HRESULT) {
+	mu := handleLock(handleKey{s.id, handle})
+	mu.Lock()
+	defer mu.Unlock()
+
+	return s.b.GetPKey(handle)
+}
+
+func (s serializedBackend) IsActivated(handle uint32) HRESULT {
+	mu := handleLock(handleKey{s.id, handle})
+	mu.Lock()
+	defer mu.Unlock()
+
+	return s.b.IsActivated(handle)
+}
+
+func (s serializedBackend) IsDateValid(handle uint32, dateTime string, flags TADateCheckFlags) HRESULT {
+	mu := handleLock(handleKey{s.id, handle})
+	mu.Lock()
+	defer mu.Unlock()
+
+	return s.b.IsDateValid(handle, dateTime, flags)
+}
+
+func (s serializedBackend) IsGenuine(handle uint32) HRESULT {
+	mu := handleLock(handleKey{s.id, handle})
+	mu.Lock()
+	defer mu.Unlock()
+
+	return s.b.IsGenuine(handle)
+}
+
+func (s serializedBackend) IsGenuineEx(handle uint32, daysBetweenChecks uint32, graceDaysOnInetErr uint32, skipOffline bool, offlineShowInetErr bool) HRESULT {
+	mu := handleLock(handleKey{s.id, handle})
+	mu.Lock()
+	defer mu.Unlock()
+
+	return s.b.IsGenuineEx(handle, daysBetweenChecks, graceDaysOnInetErr, skipOffline, offlineShowInetErr)
+}
+
+func (s serializedBackend) GenuineDays(handle uint32, daysBetweenChecks uint32, graceDaysOnInetErr uint32) (uint32, bool, HRESULT) {
+	mu := handleLock(handleKey{s.id, handle})
+	mu.Lock()
+	defer mu.Unlock()
+
+	return s.b.GenuineDays(handle, daysBetweenChecks, graceDaysOnInetErr)
+}
+
+func (s serializedBackend) IsProductKeyValid(handle uint32) HRESULT {
+	mu := handleLock(handleKey{s.id, handle})
+	mu.Lock()
+	defer mu.Unlock()
+
+	return s.b.IsProductKeyValid(handle)
+}
+
+func (s serializedBackend) TrialDaysRemaining(handle uint32, flags TAFlags) (uint32, HRESULT) {
+	mu := handleLock(handleKey{s.id, handle})
+	mu.Lock()
+	defer mu.Unlock()
+
+	return s.b.TrialDaysRemaining(handle, flags)
+}
+
+func (s serializedBackend) UseTrial(handle uint32, flags TAFlags, extraData string) HRESULT {
+	mu := handleLock(handleKey{s.id, handle})
+	mu.Lock()
+	defer mu.Unlock()
+
+	return s.b.UseTrial(handle, flags, extraData)
+}
+
+func (s serializedBackend) UseTrialVerifiedRequest(handle uint32, filename string, extraData string) HRESULT {
+	mu := handleLock(handleKey{s.id, handle})
+	mu.Lock()
+	defer mu.Unlock()
+
+	return s.b.UseTrialVerifiedRequest(handle, filename, extraData)
+}
+
+func (s serializedBackend) UseTrialVerifiedFromFile(handle uint32, filename string, flags TAFlags) HRESULT {
+	mu := handleLock(handleKey{s.id, handle})
+	mu.Lock()
+	defer mu.Unlock()
+
+	return s.b.UseTrialVerifiedFromFile(handle, filename, flags)
+}
+
+func (s serializedBackend) ExtendTrial(handle uint32, flags TAFlags, trialExtension string) HRESULT {
+	mu := handleLock(handleKey{s.id, handle})
+	mu.Lock()
+	defer mu.Unlock()
+
+	return s.b.ExtendTrial(handle, flags, trialExtension)
+}
+
+func (s serializedBackend) SetCustomActDataPath(handle uint32, directory string) HRESULT {
+	mu := handleLock(handleKey{s.id, handle})
+	mu.Lock()
+	defer mu.Unlock()
+
+	return s.b.SetCustomActDataPath(handle, directory)
+}
+
+func (s serializedBackend) SetTrialCallback(handle uint32, callback func(status uint32)) HRESULT {
+	mu := handleLock(handleKey{s.id, handle})
+	mu.Lock()
+	defer mu.Unlock()
+
+	return s.b.SetTrialCallback(handle, callback)
+}
+
+func (s serializedBackend) BlackListKeys(handle uint32, keys []string) HRESULT {
+	mu := handleLock(handleKey{s.id, handle})
+	mu.Lock()
+	defer mu.Unlock()
+
+	return s.b.BlackListKeys(handle, keys)
+}
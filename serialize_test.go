@@ -0,0 +1,200 @@
+// Copyright 2018 wyDay, LLC. All rights reserved.
+
+package turboactivate_test
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.wyday.com/turboactivate"
+	"golang.wyday.com/turboactivate/fake"
+	"golang.wyday.com/turboactivate/internal/tatest"
+)
+
+const (
+	testGUID  = tatest.GUID
+	testGUID2 = tatest.GUID2
+	testPKey  = tatest.PKey
+)
+
+// overlapBackend records whether two calls for the same handle ever ran at
+// the same time.
+type overlapBackend struct {
+	turboactivate.Backend
+
+	running  atomic.Int32
+	overlaps atomic.Int32
+}
+
+func (o *overlapBackend) enter() func() {
+	if o.running.Add(1) > 1 {
+		o.overlaps.Add(1)
+	}
+
+	// give the other goroutines a chance to overlap
+	time.Sleep(10 * time.Microsecond)
+
+	return func() { o.running.Add(-1) }
+}
+
+func (o *overlapBackend) Activate(handle uint32, extraData string) turboactivate.HRESULT {
+	defer o.enter()()
+	return o.Backend.Activate(handle, extraData)
+}
+
+func (o *overlapBackend) IsGenuineEx(handle uint32, daysBetweenChecks uint32, graceDaysOnInetErr uint32, skipOffline bool, offlineShowInetErr bool) turboactivate.HRESULT {
+	defer o.enter()()
+	return o.Backend.IsGenuineEx(handle, daysBetweenChecks, graceDaysOnInetErr, skipOffline, offlineShowInetErr)
+}
+
+func (o *overlapBackend) GetFeatureValue(handle uint32, featureName string) (string, turboactivate.HRESULT) {
+	defer o.enter()()
+	return o.Backend.GetFeatureValue(handle, featureName)
+}
+
+func TestConcurrentCallsAreSerialized(t *testing.T) {
+	b := fake.New()
+	b.AddKey(testGUID, testPKey, fake.Key{Features: map[string]string{"seats": "5"}})
+
+	var o = &overlapBackend{}
+
+	wrap := turboactivate.WithBackendWrapper(func(inner turboactivate.Backend) turboactivate.Backend {
+		o.Backend = inner
+		return o
+	})
+
+	ta, err := turboactivate.New(testGUID, turboactivate.WithBackend(b), wrap)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer ta.Close()
+
+	// a second instance for the same VersionGUID shares the handle
+	ta2, err := turboactivate.New(testGUID, turboactivate.WithBackend(b), wrap)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer ta2.Close()
+
+	if _, err = ta.CheckAndSavePKey(testPKey, turboactivate.TAUser); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 16; i++ {
+		var inst = ta
+
+		if i%2 == 1 {
+			inst = ta2
+		}
+
+		wg.Add(1)
+
+		go func(inst *turboactivate.TurboActivate) {
+			defer wg.Done()
+
+			for j := 0; j < 20; j++ {
+				if err := inst.Activate(""); err != nil {
+					t.Error(err)
+					return
+				}
+
+				if _, err := inst.IsGenuineEx(90, 14, false, false); err != nil {
+					t.Error(err)
+					return
+				}
+
+				if seats, err := inst.GetFeatureValue("seats"); err != nil || seats != "5" {
+					t.Errorf("GetFeatureValue = %q, %v; want \"5\"", seats, err)
+					return
+				}
+			}
+		}(inst)
+	}
+
+	wg.Wait()
+
+	if n := o.overlaps.Load(); n != 0 {
+		t.Errorf("%d calls for the same handle ran at the same time", n)
+	}
+}
+
+// blockingBackend blocks Activate until release is closed.
+type blockingBackend struct {
+	turboactivate.Backend
+
+	entered chan struct{}
+	release chan struct{}
+}
+
+func (bb *blockingBackend) Activate(handle uint32, extraData string) turboactivate.HRESULT {
+	close(bb.entered)
+	<-bb.release
+	return bb.Backend.Activate(handle, extraData)
+}
+
+func TestHandlesOfDifferentBackendsDontShareLocks(t *testing.T) {
+	b1 := fake.New()
+	b1.AddKey(testGUID, testPKey, fake.Key{})
+
+	b2 := fake.New()
+	b2.AddKey(testGUID2, testPKey, fake.Key{})
+
+	var bb = &blockingBackend{entered: make(chan struct{}), release: make(chan struct{})}
+
+	ta1, err := turboactivate.New(testGUID, turboactivate.WithBackend(b1),
+		turboactivate.WithBackendWrapper(func(inner turboactivate.Backend) turboactivate.Backend {
+			bb.Backend = inner
+			return bb
+		}))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer ta1.Close()
+
+	ta2, err := turboactivate.New(testGUID2, turboactivate.WithBackend(b2))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer ta2.Close()
+
+	// both fake backends hand out handle 1
+	ta1.CheckAndSavePKey(testPKey, turboactivate.TAUser)
+	ta2.CheckAndSavePKey(testPKey, turboactivate.TAUser)
+
+	var done = make(chan error, 1)
+
+	go func() { done <- ta1.Activate("") }()
+
+	<-bb.entered
+
+	var activated = make(chan error, 1)
+
+	go func() { activated <- ta2.Activate("") }()
+
+	select {
+	case err = <-activated:
+		if err != nil {
+			t.Error(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("the call on the second backend waited for the first backend's call")
+	}
+
+	close(bb.release)
+
+	if err = <-done; err != nil {
+		t.Error(err)
+	}
+}
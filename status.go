@@ -0,0 +1,255 @@
+// Copyright 2018 wyDay, LLC. All rights reserved.
+
+package turboactivate // import "golang.wyday.com/turboactivate"
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// String returns the name of the result (e.g. "Genuine").
+func (r IsGenuineResult) String() string {
+	switch r {
+	case IGRGenuine:
+		return "Genuine"
+	case IGRGenuineFeaturesChanged:
+		return "GenuineFeaturesChanged"
+	case IGRNotGenuine:
+		return "NotGenuine"
+	case IGRNotGenuineInVM:
+		return "NotGenuineInVM"
+	case IGRInternetError:
+		return "InternetError"
+	default:
+		return "IsGenuineResult(" + strconv.Itoa(int(r)) + ")"
+	}
+}
+
+// MarshalText encodes the result as its name, so it's readable in JSON and logs.
+func (r IsGenuineResult) MarshalText() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+// UnmarshalText decodes the name of a result.
+func (r *IsGenuineResult) UnmarshalText(text []byte) error {
+	for _, res := range []IsGenuineResult{IGRGenuine, IGRGenuineFeaturesChanged, IGRNotGenuine, IGRNotGenuineInVM, IGRInternetError} {
+		if res.String() == string(text) {
+			*r = res
+			return nil
+		}
+	}
+
+	return errors.New("unknown IsGenuineResult \"" + string(text) + "\"")
+}
+
+// MaskProductKey hides every group of the product key except the last one
+// (e.g. "XXXX-XXXX-XXXX-XXXX-XXXX-XXXX-GGGG"), so it can be logged and shown.
+func MaskProductKey(pkey string) string {
+	if pkey == "" {
+		return ""
+	}
+
+	var groups = strings.Split(pkey, "-")
+
+	if len(groups) == 1 {
+		return strings.Repeat("X", len(pkey))
+	}
+
+	for i := 0; i < len(groups)-1; i++ {
+		groups[i] = strings.Repeat("X", len(groups[i]))
+	}
+
+	return strings.Join(groups, "-")
+}
+
+// StatusOptions configures Status(). The zero value of each field means
+// the default is used.
+type StatusOptions struct {
+	// DaysBetweenChecks and GraceDaysOnInetErr are passed to IsGenuineEx()
+	// and GenuineDays(). They default to 90 and 14.
+	DaysBetweenChecks  uint32
+	GraceDaysOnInetErr uint32
+
+	// SkipOffline and OfflineShowInetErr are passed to IsGenuineEx().
+	SkipOffline        bool
+	OfflineShowInetErr bool
+
+	// SkipGenuineCheck skips IsGenuineEx() so Status() never contacts the
+	// LimeLM servers. GenuineResult is then derived from IsActivated().
+	SkipGenuineCheck bool
+
+	// TrialFlags are passed to TrialDaysRemaining(). Defaults to DefaultFlags().
+	TrialFlags TAFlags
+
+	// Features are the names of the custom license fields to read.
+	Features []string
+}
+
+// LicenseStatus is a snapshot of the licensing state of the product.
+// It never contains the full product key.
+type LicenseStatus struct {
+	// HasKey is whether a valid product key is saved.
+	HasKey bool `json:"has_key"`
+
+	// Activated is whether the product is activated on this computer.
+	Activated bool `json:"activated"`
+
+	// GenuineResult is the result of IsGenuineEx().
+	GenuineResult IsGenuineResult `json:"genuine_result"`
+
+	// DaysUntilRecheck and InGracePeriod are the result of GenuineDays().
+	DaysUntilRecheck uint32 `json:"days_until_recheck"`
+	InGracePeriod    bool   `json:"in_grace_period"`
+
+	// TrialStarted is whether UseTrial() was called before.
+	TrialStarted bool `json:"trial_started"`
+
+	// TrialDaysLeft is the result of TrialDaysRemaining().
+	TrialDaysLeft uint32 `json:"trial_days_left"`
+
+	// TrialType is "verified" or "unverified" if the trial was started.
+	TrialType string `json:"trial_type,omitempty"`
+
+	// MaskedKey is the product key with all but the last group hidden.
+	MaskedKey string `json:"masked_key,omitempty"`
+
+	// ExtraData is the extra data passed when activating.
+	ExtraData string `json:"extra_data,omitempty"`
+
+	// Features are the values of the StatusOptions.Features that exist.
+	Features map[string]string `json:"features,omitempty"`
+
+	// CheckedAt is when the snapshot was taken.
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// Status gets a snapshot of the licensing state of the product by calling
+// IsProductKeyValid(), GetPKey(), IsActivated(), IsGenuineEx(), GenuineDays(),
+// GetExtraData(), TrialDaysRemaining() and GetFeatureValue().
+//
+// The results that only describe the state (no product key, not activated,
+// trial not started, feature missing) aren't errors. If one of the functions
+// fails with any other error, Status returns the partial snapshot along with
+// the error. IsGenuineEx() is the only call that can contact the LimeLM
+// servers, and it's abandoned if ctx is done.
+func (ta *TurboActivate) Status(ctx context.Context, opts StatusOptions) (LicenseStatus, error) {
+
+	if opts.DaysBetweenChecks == 0 {
+		opts.DaysBetweenChecks = 90
+	}
+
+	if opts.GraceDaysOnInetErr == 0 {
+		opts.GraceDaysOnInetErr = 14
+	}
+
+	if opts.TrialFlags == 0 {
+		opts.TrialFlags = ta.DefaultFlags()
+	}
+
+	var st = LicenseStatus{
+		GenuineResult: IGRNotGenuine,
+		CheckedAt:     time.Now().UTC(),
+	}
+
+	var err error
+
+	if st.HasKey, err = ta.IsProductKeyValid(); err != nil {
+		return st, err
+	}
+
+	if st.HasKey {
+		pkey, err := ta.GetPKey()
+
+		if err != nil {
+			return st, err
+		}
+
+		st.MaskedKey = MaskProductKey(pkey)
+	}
+
+	if st.Activated, err = ta.IsActivated(); err != nil {
+		return st, err
+	}
+
+	if opts.SkipGenuineCheck {
+		if st.Activated {
+			st.GenuineResult = IGRGenuine
+		}
+	} else if st.GenuineResult, err = ta.IsGenuineExContext(ctx, opts.DaysBetweenChecks, opts.GraceDaysOnInetErr, opts.SkipOffline, opts.OfflineShowInetErr); err != nil {
+		return st, err
+	}
+
+	// IsGenuineEx() can deactivate the product (e.g. revoked keys)
+	if st.GenuineResult == IGRNotGenuine && st.Activated {
+		if st.Activated, err = ta.IsActivated(); err != nil {
+			return st, err
+		}
+	}
+
+	if st.Activated {
+		if st.DaysUntilRecheck, st.InGracePeriod, err = ta.GenuineDays(opts.DaysBetweenChecks, opts.GraceDaysOnInetErr); err != nil {
+			return st, err
+		}
+
+		if st.ExtraData, err = ta.GetExtraData(); err != nil {
+			return st, err
+		}
+	}
+
+	days, err := ta.TrialDaysRemaining(opts.TrialFlags)
+
+	switch {
+	case err == nil:
+		st.TrialStarted = true
+		st.TrialDaysLeft = days
+
+		if opts.TrialFlags&TAUnverifiedTrial != 0 {
+			st.TrialType = "unverified"
+		} else {
+			st.TrialType = "verified"
+		}
+
+	case errors.Is(err, ErrAlreadyVerifiedTrial):
+		st.TrialStarted = true
+		st.TrialType = "verified"
+
+		if days, err = ta.TrialDaysRemaining(opts.TrialFlags&^TAUnverifiedTrial | TAVerifiedTrial); err != nil {
+			return st, err
+		}
+
+		st.TrialDaysLeft = days
+
+	case errors.Is(err, ErrMustUseTrial), errors.Is(err, ErrMustSpecifyTrialType):
+
+	default:
+		return st, err
+	}
+
+	for _, name := range opts.Features {
+		if err = ctx.Err(); err != nil {
+			return st, err
+		}
+
+		value, err := ta.GetFeatureValue(name)
+
+		if err != nil {
+			// TA_FAIL means the feature doesn't exist, TA_E_PKEY means there's no product key
+			if errors.Is(err, ErrFail) || errors.Is(err, ErrInvalidPKey) {
+				continue
+			}
+
+			return st, err
+		}
+
+		if st.Features == nil {
+			st.Features = map[string]string{}
+		}
+
+		st.Features[name] = value
+	}
+
+	return st, nil
+}
@@ -0,0 +1,137 @@
+// Copyright 2018 wyDay, LLC. All rights reserved.
+
+package turboactivate_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"golang.wyday.com/turboactivate"
+	"golang.wyday.com/turboactivate/fake"
+	"golang.wyday.com/turboactivate/internal/tatest"
+)
+
+func TestStatus(t *testing.T) {
+	b := fake.New()
+	b.AddProduct(testGUID, fake.Product{TrialDays: 30})
+
+	ta := tatest.New(t, b, tatest.WithKey(map[string]string{"seats": "5"}))
+
+	var flags = turboactivate.TAUser | turboactivate.TAVerifiedTrial
+	var opts = turboactivate.StatusOptions{TrialFlags: flags, Features: []string{"seats", "missing"}}
+
+	if _, err := ta.UseTrial(flags, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	st, err := ta.Status(context.Background(), opts)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !st.HasKey || st.Activated || st.GenuineResult != turboactivate.IGRNotGenuine || !st.TrialStarted || st.TrialDaysLeft != 30 || st.TrialType != "verified" {
+		t.Errorf("Status() before activating = %+v", st)
+	}
+
+	if st.MaskedKey != "XXXX-XXXX-XXXX-XXXX-XXXX-XXXX-GGGG" {
+		t.Errorf("MaskedKey = %q", st.MaskedKey)
+	}
+
+	if err = ta.Activate("extra"); err != nil {
+		t.Fatal(err)
+	}
+
+	if st, err = ta.Status(context.Background(), opts); err != nil {
+		t.Fatal(err)
+	}
+
+	if !st.Activated || st.GenuineResult != turboactivate.IGRGenuine || st.DaysUntilRecheck != 90 || st.ExtraData != "extra" {
+		t.Errorf("Status() after activating = %+v", st)
+	}
+
+	if len(st.Features) != 1 || st.Features["seats"] != "5" {
+		t.Errorf("Features = %v; want only seats", st.Features)
+	}
+
+	// the JSON never has the product key
+	data, err := json.Marshal(st)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(string(data), testPKey) || !strings.Contains(string(data), `"genuine_result":"Genuine"`) {
+		t.Errorf("Status() JSON = %s", data)
+	}
+
+	// SkipGenuineCheck doesn't contact the servers
+	var calls = b.Calls("IsGenuineEx")
+
+	opts.SkipGenuineCheck = true
+
+	if st, err = ta.Status(context.Background(), opts); err != nil || st.GenuineResult != turboactivate.IGRGenuine {
+		t.Errorf("Status() without the genuine check = %+v, %v", st, err)
+	}
+
+	if n := b.Calls("IsGenuineEx"); n != calls {
+		t.Error("IsGenuineEx was called with SkipGenuineCheck")
+	}
+}
+
+func TestStatusError(t *testing.T) {
+	b := fake.New()
+
+	ta := tatest.New(t, b, tatest.WithKey(nil))
+
+	b.FailNext("IsActivated", turboactivate.TAEPermission)
+
+	st, err := ta.Status(context.Background(), turboactivate.StatusOptions{})
+
+	if !errors.Is(err, turboactivate.ErrPermission) {
+		t.Errorf("Status() = %v; want ErrPermission", err)
+	}
+
+	// the partial status is returned along with the error
+	if !st.HasKey || st.CheckedAt.IsZero() {
+		t.Errorf("Status() = %+v; want the partial status", st)
+	}
+}
+
+func TestMaskProductKey(t *testing.T) {
+	for pkey, want := range map[string]string{
+		"":                                   "",
+		"ABCD":                               "XXXX",
+		"AB-CDE-FGHI":                        "XX-XXX-FGHI",
+		"AAAA-BBBB-CCCC-DDDD-EEEE-FFFF-GGGG": "XXXX-XXXX-XXXX-XXXX-XXXX-XXXX-GGGG",
+	} {
+		if masked := turboactivate.MaskProductKey(pkey); masked != want {
+			t.Errorf("MaskProductKey(%q) = %q; want %q", pkey, masked, want)
+		}
+	}
+}
+
+func TestIsGenuineResultText(t *testing.T) {
+	for _, r := range []turboactivate.IsGenuineResult{turboactivate.IGRGenuine, turboactivate.IGRGenuineFeaturesChanged, turboactivate.IGRNotGenuine, turboactivate.IGRNotGenuineInVM, turboactivate.IGRInternetError} {
+		text, err := r.MarshalText()
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var decoded turboactivate.IsGenuineResult
+
+		if err = decoded.UnmarshalText(text); err != nil || decoded != r {
+			t.Errorf("UnmarshalText(%s) = %v, %v; want %v", text, decoded, err, r)
+		}
+	}
+
+	var r turboactivate.IsGenuineResult
+
+	if err := r.UnmarshalText([]byte("Maybe")); err == nil {
+		t.Error("UnmarshalText() of an unknown result succeeded")
+	}
+}
@@ -0,0 +1,200 @@
+// Copyright 2018 wyDay, LLC. All rights reserved.
+
+// Package tahttp serves the licensing status of a product over HTTP,
+// for dashboards and health checks (e.g. Kubernetes probes):
+//
+//	h := tahttp.NewHandler(ta, tahttp.Options{
+//		Status: turboactivate.StatusOptions{Features: []string{"seats"}},
+//	})
+//	http.Handle("/license", h)
+//	http.Handle("/ready", h.Ready())
+//
+// The status is cached, so frequent requests don't make TurboActivate
+// contact the LimeLM servers on every request.
+package tahttp // import "golang.wyday.com/turboactivate/tahttp"
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.wyday.com/turboactivate"
+)
+
+// DefaultCacheTTL is how long the status is cached by default.
+const DefaultCacheTTL = 5 * time.Minute
+
+// DefaultTimeout is how long getting the status can take by default.
+const DefaultTimeout = 30 * time.Second
+
+// Options configures a Handler. The zero value of each field means the default is used.
+type Options struct {
+	// Status is passed to TurboActivate.Status().
+	Status turboactivate.StatusOptions
+
+	// CacheTTL is how long the status is cached. Defaults to DefaultCacheTTL.
+	CacheTTL time.Duration
+
+	// Timeout is how long getting the status can take. Defaults to DefaultTimeout.
+	Timeout time.Duration
+}
+
+// Response is the JSON body served by the handlers. ExtraData is always empty.
+type Response struct {
+	turboactivate.LicenseStatus
+
+	// Error is the error Status() returned (if any).
+	Error string `json:"error,omitempty"`
+}
+
+// Handler serves the licensing status as JSON. It responds with
+// 200 OK, or with 500 Internal Server Error if getting the status failed.
+// The extra data of the activation isn't served. It's safe for concurrent use.
+type Handler struct {
+	ta   *turboactivate.TurboActivate
+	opts Options
+
+	// mu guards the cached status. Only one refresh runs at a time: the
+	// requests that come in while it runs get the cached status (even if it
+	// expired), or wait for the refresh if nothing is cached yet.
+	mu         sync.Mutex
+	status     turboactivate.LicenseStatus
+	err        error
+	cached     bool
+	expires    time.Time
+	refreshing chan struct{}
+
+	// gen changes when the cache is invalidated.
+	gen uint64
+}
+
+// NewHandler returns a Handler serving the status of ta.
+func NewHandler(ta *turboactivate.TurboActivate, opts Options) *Handler {
+	if opts.CacheTTL <= 0 {
+		opts.CacheTTL = DefaultCacheTTL
+	}
+
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
+
+	return &Handler{ta: ta, opts: opts}
+}
+
+// Status returns the cached status. If the cache expired, it starts getting
+// the status again and returns the expired status in the meantime. If nothing
+// is cached (or the cache was invalidated), it waits for the status.
+func (h *Handler) Status(ctx context.Context) (turboactivate.LicenseStatus, error) {
+	for {
+		h.mu.Lock()
+
+		if h.refreshing == nil && (!h.cached || !time.Now().Before(h.expires)) {
+			// the refresh is shared, so it isn't canceled with the request
+			h.refreshing = make(chan struct{})
+			go h.refresh(context.WithoutCancel(ctx), h.refreshing, h.gen)
+		}
+
+		if h.cached {
+			st, err := h.status, h.err
+			h.mu.Unlock()
+			return st, err
+		}
+
+		var done = h.refreshing
+		h.mu.Unlock()
+
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return turboactivate.LicenseStatus{}, ctx.Err()
+		}
+	}
+}
+
+// refresh gets the status and caches it, unless the cache was invalidated
+// since the refresh started.
+func (h *Handler) refresh(ctx context.Context, done chan struct{}, gen uint64) {
+	ctx, cancel := context.WithTimeout(ctx, h.opts.Timeout)
+	defer cancel()
+
+	st, err := h.ta.Status(ctx, h.opts.Status)
+
+	h.mu.Lock()
+
+	if gen == h.gen {
+		h.status = st
+		h.err = err
+		h.cached = true
+		h.expires = time.Now().Add(h.opts.CacheTTL)
+	}
+
+	h.refreshing = nil
+	h.mu.Unlock()
+
+	close(done)
+}
+
+// Invalidate discards the cached status, so the next request gets it again
+// (e.g. after activating or deactivating).
+func (h *Handler) Invalidate() {
+	h.mu.Lock()
+	h.cached = false
+	h.gen++
+	h.mu.Unlock()
+}
+
+// ServeHTTP serves the status.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.serve(w, r, false)
+}
+
+// Ready returns a handler for readiness probes. It serves the same status
+// (from the same cache), but responds with 503 Service Unavailable when the
+// product isn't genuine (IGRNotGenuine or IGRNotGenuineInVM) or getting the
+// status failed. IGRInternetError is considered ready, since TurboActivate
+// has a grace period for it.
+func (h *Handler) Ready() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.serve(w, r, true)
+	})
+}
+
+func (h *Handler) serve(w http.ResponseWriter, r *http.Request, readiness bool) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	st, err := h.Status(r.Context())
+
+	// the extra data can be sensitive, and the handlers aren't authenticated
+	st.ExtraData = ""
+
+	var resp = Response{LicenseStatus: st}
+	var code = http.StatusOK
+
+	if err != nil {
+		resp.Error = err.Error()
+
+		if readiness {
+			code = http.StatusServiceUnavailable
+		} else {
+			code = http.StatusInternalServerError
+		}
+	} else if readiness && (st.GenuineResult == turboactivate.IGRNotGenuine || st.GenuineResult == turboactivate.IGRNotGenuineInVM) {
+		code = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+
+	if r.Method == http.MethodHead {
+		return
+	}
+
+	json.NewEncoder(w).Encode(resp)
+}
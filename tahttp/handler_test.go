@@ -0,0 +1,160 @@
+// Copyright 2018 wyDay, LLC. All rights reserved.
+
+package tahttp_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.wyday.com/turboactivate"
+	"golang.wyday.com/turboactivate/fake"
+	"golang.wyday.com/turboactivate/internal/tatest"
+	"golang.wyday.com/turboactivate/tahttp"
+)
+
+// gatedBackend blocks IsGenuineEx while gate is set.
+type gatedBackend struct {
+	turboactivate.Backend
+
+	gate   atomic.Pointer[chan struct{}]
+	checks atomic.Int32
+}
+
+func (g *gatedBackend) IsGenuineEx(handle uint32, daysBetweenChecks uint32, graceDaysOnInetErr uint32, skipOffline bool, offlineShowInetErr bool) turboactivate.HRESULT {
+	g.checks.Add(1)
+
+	if gate := g.gate.Load(); gate != nil {
+		<-*gate
+	}
+
+	return g.Backend.IsGenuineEx(handle, daysBetweenChecks, graceDaysOnInetErr, skipOffline, offlineShowInetErr)
+}
+
+// wrap is the WithBackendWrapper function that gates the calls of inner.
+func (g *gatedBackend) wrap(inner turboactivate.Backend) turboactivate.Backend {
+	g.Backend = inner
+	return g
+}
+
+func TestHandlerDoesntServeExtraData(t *testing.T) {
+	ta := tatest.New(t, fake.New(), tatest.WithActivation("secret customer data"))
+
+	var rec = httptest.NewRecorder()
+	tahttp.NewHandler(ta, tahttp.Options{}).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/license", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("code = %d; want 200", rec.Code)
+	}
+
+	if strings.Contains(rec.Body.String(), "secret") {
+		t.Errorf("the extra data was served: %s", rec.Body)
+	}
+
+	var resp tahttp.Response
+
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+
+	if !resp.Activated || resp.GenuineResult != turboactivate.IGRGenuine {
+		t.Errorf("response = %+v; want activated and genuine", resp)
+	}
+}
+
+func TestReadyNotGenuine(t *testing.T) {
+	ta := tatest.New(t, fake.New())
+
+	var h = tahttp.NewHandler(ta, tahttp.Options{})
+	var rec = httptest.NewRecorder()
+
+	h.Ready().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ready", nil))
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("ready code = %d; want 503", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/license", nil))
+
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST code = %d; want 405", rec.Code)
+	}
+}
+
+func TestStatusServesCacheWhileRefreshing(t *testing.T) {
+	var g = &gatedBackend{}
+
+	ta := tatest.New(t, fake.New(), tatest.WithActivation(""), tatest.WithOptions(turboactivate.WithBackendWrapper(g.wrap)))
+
+	var h = tahttp.NewHandler(ta, tahttp.Options{CacheTTL: time.Millisecond})
+
+	if _, err := h.Status(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	var gate = make(chan struct{})
+	g.gate.Store(&gate)
+
+	time.Sleep(5 * time.Millisecond)
+
+	var checks = g.checks.Load()
+
+	// the cache expired: the refresh blocks, but the requests don't wait for it
+	for i := 0; i < 3; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		st, err := h.Status(ctx)
+		cancel()
+
+		if err != nil || !st.Activated {
+			t.Fatalf("Status() = %+v, %v; want the cached status", st, err)
+		}
+	}
+
+	g.gate.Store(nil)
+	close(gate)
+
+	var deadline = time.Now().Add(5 * time.Second)
+
+	for g.checks.Load() == checks {
+		if time.Now().After(deadline) {
+			t.Fatal("the status wasn't refreshed")
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+
+	if n := g.checks.Load() - checks; n != 1 {
+		t.Errorf("%d refreshes ran; want 1", n)
+	}
+}
+
+func TestInvalidateWaitsForNewStatus(t *testing.T) {
+	ta := tatest.New(t, fake.New(), tatest.WithActivation(""))
+
+	var h = tahttp.NewHandler(ta, tahttp.Options{})
+
+	if st, err := h.Status(context.Background()); err != nil || !st.Activated {
+		t.Fatalf("Status() = %+v, %v; want activated", st, err)
+	}
+
+	if err := ta.Deactivate(false); err != nil {
+		t.Fatal(err)
+	}
+
+	// still cached
+	if st, _ := h.Status(context.Background()); !st.Activated {
+		t.Error("the status wasn't cached")
+	}
+
+	h.Invalidate()
+
+	if st, err := h.Status(context.Background()); err != nil || st.Activated {
+		t.Errorf("Status() = %+v, %v; want deactivated", st, err)
+	}
+}
@@ -0,0 +1,158 @@
+// Copyright 2018 wyDay, LLC. All rights reserved.
+
+// Package taotel traces the TurboActivate functions that contact the LimeLM
+// servers with OpenTelemetry.
+//
+// Wrap the TurboActivate instance and call the functions with the context of
+// the caller, so the spans are children of the caller's span:
+//
+//	t := taotel.New(ta)
+//	err := t.Activate(ctx, "")
+//
+// Each span is named after the function (e.g. "turboactivate.Activate") and
+// has these attributes (when they apply):
+//
+//	turboactivate.hresult         the name of the HRESULT of the failed call (e.g. "TA_E_INET")
+//	turboactivate.genuine_result  the IsGenuineResult (e.g. "Genuine")
+//	turboactivate.trial_active    the result of UseTrial
+//	turboactivate.attempts        the number of attempts, if the call was retried (see RetryPolicy)
+package taotel // import "golang.wyday.com/turboactivate/taotel"
+
+import (
+	"context"
+	"errors"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"golang.wyday.com/turboactivate"
+)
+
+// instrumentationName is the name of the tracer.
+const instrumentationName = "golang.wyday.com/turboactivate/taotel"
+
+// Option configures New.
+type Option func(*TurboActivate)
+
+// WithTracerProvider makes New use tp instead of the global tracer provider.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(t *TurboActivate) {
+		t.tracer = tp.Tracer(instrumentationName)
+	}
+}
+
+// TurboActivate wraps a TurboActivate instance and traces the calls of its
+// functions that contact the LimeLM servers.
+type TurboActivate struct {
+	ta     *turboactivate.TurboActivate
+	tracer trace.Tracer
+}
+
+// New wraps ta. The spans are created with the global tracer provider
+// unless WithTracerProvider is used.
+func New(ta *turboactivate.TurboActivate, opts ...Option) *TurboActivate {
+	var t = &TurboActivate{ta: ta}
+
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	if t.tracer == nil {
+		t.tracer = otel.GetTracerProvider().Tracer(instrumentationName)
+	}
+
+	return t
+}
+
+// Unwrap returns the wrapped TurboActivate instance, to call the functions that aren't traced.
+func (t *TurboActivate) Unwrap() *turboactivate.TurboActivate {
+	return t.ta
+}
+
+func (t *TurboActivate) start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return t.tracer.Start(ctx, "turboactivate."+name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+}
+
+// end records err (if any) on the span and ends it.
+func end(span trace.Span, err error) {
+	if err != nil {
+		var taErr *turboactivate.TAError
+
+		if errors.As(err, &taErr) {
+			span.SetAttributes(attribute.String("turboactivate.hresult", taErr.Code.String()))
+		}
+
+		var retryErr *turboactivate.RetryError
+
+		if errors.As(err, &retryErr) {
+			span.SetAttributes(attribute.Int("turboactivate.attempts", retryErr.Attempts))
+		}
+
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
+
+// Activate calls ActivateContext in a span.
+func (t *TurboActivate) Activate(ctx context.Context, extraData string) error {
+	ctx, span := t.start(ctx, "Activate")
+
+	var err = t.ta.ActivateContext(ctx, extraData)
+	end(span, err)
+	return err
+}
+
+// IsGenuine calls IsGenuineContext in a span.
+func (t *TurboActivate) IsGenuine(ctx context.Context) (turboactivate.IsGenuineResult, error) {
+	ctx, span := t.start(ctx, "IsGenuine")
+
+	res, err := t.ta.IsGenuineContext(ctx)
+	span.SetAttributes(attribute.String("turboactivate.genuine_result", res.String()))
+	end(span, err)
+	return res, err
+}
+
+// IsGenuineEx calls IsGenuineExContext in a span.
+func (t *TurboActivate) IsGenuineEx(ctx context.Context, daysBetweenChecks uint32, graceDaysOnInetErr uint32, skipOffline bool, offlineShowInetErr bool) (turboactivate.IsGenuineResult, error) {
+	ctx, span := t.start(ctx, "IsGenuineEx",
+		attribute.Int("turboactivate.days_between_checks", int(daysBetweenChecks)),
+		attribute.Int("turboactivate.grace_days_on_inet_err", int(graceDaysOnInetErr)),
+		attribute.Bool("turboactivate.skip_offline", skipOffline))
+
+	res, err := t.ta.IsGenuineExContext(ctx, daysBetweenChecks, graceDaysOnInetErr, skipOffline, offlineShowInetErr)
+	span.SetAttributes(attribute.String("turboactivate.genuine_result", res.String()))
+	end(span, err)
+	return res, err
+}
+
+// Deactivate calls DeactivateContext in a span.
+func (t *TurboActivate) Deactivate(ctx context.Context, eraseProductKey bool) error {
+	ctx, span := t.start(ctx, "Deactivate", attribute.Bool("turboactivate.erase_product_key", eraseProductKey))
+
+	var err = t.ta.DeactivateContext(ctx, eraseProductKey)
+	end(span, err)
+	return err
+}
+
+// UseTrial calls UseTrialContext in a span.
+func (t *TurboActivate) UseTrial(ctx context.Context, flags turboactivate.TAFlags, extraData string) (bool, error) {
+	ctx, span := t.start(ctx, "UseTrial", attribute.Int("turboactivate.flags", int(flags)))
+
+	ok, err := t.ta.UseTrialContext(ctx, flags, extraData)
+	span.SetAttributes(attribute.Bool("turboactivate.trial_active", ok))
+	end(span, err)
+	return ok, err
+}
+
+// ExtendTrial calls ExtendTrialContext in a span.
+func (t *TurboActivate) ExtendTrial(ctx context.Context, trialExtension string, flags turboactivate.TAFlags) error {
+	ctx, span := t.start(ctx, "ExtendTrial", attribute.Int("turboactivate.flags", int(flags)))
+
+	var err = t.ta.ExtendTrialContext(ctx, trialExtension, flags)
+	end(span, err)
+	return err
+}
@@ -0,0 +1,95 @@
+// Copyright 2018 wyDay, LLC. All rights reserved.
+
+package taotel_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"golang.wyday.com/turboactivate"
+	"golang.wyday.com/turboactivate/fake"
+	"golang.wyday.com/turboactivate/internal/tatest"
+	"golang.wyday.com/turboactivate/taotel"
+)
+
+// attr returns the value of the attribute of the span, if it has it.
+func attr(span sdktrace.ReadOnlySpan, key attribute.Key) (attribute.Value, bool) {
+	for _, kv := range span.Attributes() {
+		if kv.Key == key {
+			return kv.Value, true
+		}
+	}
+
+	return attribute.Value{}, false
+}
+
+// trace wraps ta with a tracer provider that records the spans.
+func trace(ta *turboactivate.TurboActivate) (*taotel.TurboActivate, *tracetest.SpanRecorder) {
+	var rec = tracetest.NewSpanRecorder()
+	var tp = sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec))
+
+	return taotel.New(ta, taotel.WithTracerProvider(tp)), rec
+}
+
+func TestSpans(t *testing.T) {
+	traced, rec := trace(tatest.New(t, fake.New(), tatest.WithKey(nil)))
+
+	if err := traced.Activate(context.Background(), ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := traced.IsGenuineEx(context.Background(), 90, 14, false, false); err != nil {
+		t.Fatal(err)
+	}
+
+	var spans = rec.Ended()
+
+	if len(spans) != 2 || spans[0].Name() != "turboactivate.Activate" || spans[1].Name() != "turboactivate.IsGenuineEx" {
+		t.Fatalf("spans = %v", spans)
+	}
+
+	if spans[0].Status().Code == codes.Error {
+		t.Errorf("the Activate span failed: %v", spans[0].Status())
+	}
+
+	if v, ok := attr(spans[1], "turboactivate.genuine_result"); !ok || v.AsString() != "Genuine" {
+		t.Errorf("turboactivate.genuine_result = %v", v.Emit())
+	}
+
+	if v, ok := attr(spans[1], "turboactivate.days_between_checks"); !ok || v.AsInt64() != 90 {
+		t.Errorf("turboactivate.days_between_checks = %v", v.Emit())
+	}
+}
+
+func TestSpanErrors(t *testing.T) {
+	b := fake.New()
+
+	traced, rec := trace(tatest.New(t, b, tatest.WithKey(nil),
+		tatest.WithOptions(turboactivate.WithRetryPolicy(turboactivate.RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond}))))
+
+	b.Fail("Activate", turboactivate.TAEInet)
+
+	if err := traced.Activate(context.Background(), ""); err == nil {
+		t.Fatal("Activate() succeeded")
+	}
+
+	var span = rec.Ended()[0]
+
+	if span.Status().Code != codes.Error || len(span.Events()) == 0 {
+		t.Errorf("the span doesn't have the error: %v, %v", span.Status(), span.Events())
+	}
+
+	if v, ok := attr(span, "turboactivate.hresult"); !ok || v.AsString() != "TA_E_INET" {
+		t.Errorf("turboactivate.hresult = %v", v.Emit())
+	}
+
+	if v, ok := attr(span, "turboactivate.attempts"); !ok || v.AsInt64() != 2 {
+		t.Errorf("turboactivate.attempts = %v", v.Emit())
+	}
+}
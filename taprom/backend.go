@@ -0,0 +1,235 @@
+// Copyright 2018 wyDay, LLC. All rights reserved.
+
+package taprom // import "golang.wyday.com/turboactivate/taprom"
+
+import (
+	"time"
+
+	"golang.wyday.com/turboactivate"
+)
+
+// backend records the metrics of the calls to the wrapped backend.
+type backend struct {
+	b turboactivate.Backend
+	m *Metrics
+}
+
+// Wrap returns a backend that records the duration and HRESULT of every call to b.
+func (m *Metrics) Wrap(b turboactivate.Backend) turboactivate.Backend {
+	return backend{b: b, m: m}
+}
+
+// observe records a call to function that started at start and returned ret.
+func (m *Metrics) observe(function string, start time.Time, ret turboactivate.HRESULT) {
+	m.duration.WithLabelValues(function).Observe(time.Since(start).Seconds())
+	m.calls.WithLabelValues(function, ret.String()).Inc()
+}
+
+func (w backend) PDetsFromPath(filename string) turboactivate.HRESULT {
+	var start = time.Now()
+	var ret = w.b.PDetsFromPath(filename)
+	w.m.observe("PDetsFromPath", start, ret)
+	return ret
+}
+
+func (w backend) PDetsFromByteArray(data []byte) turboactivate.HRESULT {
+	var start = time.Now()
+	var ret = w.b.PDetsFromByteArray(data)
+	w.m.observe("PDetsFromByteArray", start, ret)
+	return ret
+}
+
+func (w backend) GetHandle(versionGUID string) uint32 {
+	var start = time.Now()
+	var handle = w.b.GetHandle(versionGUID)
+	var ret = turboactivate.TAOK
+
+	if handle == 0 {
+		ret = turboactivate.TAEInvalidHandle
+	}
+
+	w.m.observe("GetHandle", start, ret)
+	return handle
+}
+
+func (w backend) Activate(handle uint32, extraData string) turboactivate.HRESULT {
+	var start = time.Now()
+	var ret = w.b.Activate(handle, extraData)
+	w.m.observe("Activate", start, ret)
+	return ret
+}
+
+func (w backend) ActivationRequestToFile(handle uint32, filename string, extraData string) turboactivate.HRESULT {
+	var start = time.Now()
+	var ret = w.b.ActivationRequestToFile(handle, filename, extraData)
+	w.m.observe("ActivationRequestToFile", start, ret)
+	return ret
+}
+
+func (w backend) ActivateFromFile(handle uint32, filename string) turboactivate.HRESULT {
+	var start = time.Now()
+	var ret = w.b.ActivateFromFile(handle, filename)
+	w.m.observe("ActivateFromFile", start, ret)
+	return ret
+}
+
+func (w backend) CheckAndSavePKey(handle uint32, productKey string, flags turboactivate.TAFlags) turboactivate.HRESULT {
+	var start = time.Now()
+	var ret = w.b.CheckAndSavePKey(handle, productKey, flags)
+	w.m.observe("CheckAndSavePKey", start, ret)
+	return ret
+}
+
+func (w backend) Deactivate(handle uint32, eraseProductKey bool) turboactivate.HRESULT {
+	var start = time.Now()
+	var ret = w.b.Deactivate(handle, eraseProductKey)
+	w.m.observe("Deactivate", start, ret)
+	return ret
+}
+
+func (w backend) DeactivationRequestToFile(handle uint32, filename string, eraseProductKey bool) turboactivate.HRESULT {
+	var start = time.Now()
+	var ret = w.b.DeactivationRequestToFile(handle, filename, eraseProductKey)
+	w.m.observe("DeactivationRequestToFile", start, ret)
+	return ret
+}
+
+func (w backend) GetExtraData(handle uint32) (string, turboactivate.HRESULT) {
+	var start = time.Now()
+	extraData, ret := w.b.GetExtraData(handle)
+	w.m.observe("GetExtraData", start, ret)
+	return extraData, ret
+}
+
+func (w backend) GetFeatureValue(handle uint32, featureName string) (string, turboactivate.HRESULT) {
+	var start = time.Now()
+	value, ret := w.b.GetFeatureValue(handle, featureName)
+	w.m.observe("GetFeatureValue", start, ret)
+	return value, ret
+}
+
+func (w backend) GetPKey(handle uint32) (string, turboactivate.HRESULT) {
+	var start = time.Now()
+	pkey, ret := w.b.GetPKey(handle)
+	w.m.observe("GetPKey", start, ret)
+	return pkey, ret
+}
+
+func (w backend) IsActivated(handle uint32) turboactivate.HRESULT {
+	var start = time.Now()
+	var ret = w.b.IsActivated(handle)
+	w.m.observe("IsActivated", start, ret)
+	return ret
+}
+
+func (w backend) IsDateValid(handle uint32, dateTime string, flags turboactivate.TADateCheckFlags) turboactivate.HRESULT {
+	var start = time.Now()
+	var ret = w.b.IsDateValid(handle, dateTime, flags)
+	w.m.observe("IsDateValid", start, ret)
+	return ret
+}
+
+func (w backend) IsGenuine(handle uint32) turboactivate.HRESULT {
+	var start = time.Now()
+	var ret = w.b.IsGenuine(handle)
+	w.m.observe("IsGenuine", start, ret)
+	return ret
+}
+
+func (w backend) IsGenuineEx(handle uint32, daysBetweenChecks uint32, graceDaysOnInetErr uint32, skipOffline bool, offlineShowInetErr bool) turboactivate.HRESULT {
+	var start = time.Now()
+	var ret = w.b.IsGenuineEx(handle, daysBetweenChecks, graceDaysOnInetErr, skipOffline, offlineShowInetErr)
+	w.m.observe("IsGenuineEx", start, ret)
+	return ret
+}
+
+func (w backend) GenuineDays(handle uint32, daysBetweenChecks uint32, graceDaysOnInetErr uint32) (uint32, bool, turboactivate.HRESULT) {
+	var start = time.Now()
+	days, inGrace, ret := w.b.GenuineDays(handle, daysBetweenChecks, graceDaysOnInetErr)
+	w.m.observe("GenuineDays", start, ret)
+	return days, inGrace, ret
+}
+
+func (w backend) IsProductKeyValid(handle uint32) turboactivate.HRESULT {
+	var start = time.Now()
+	var ret = w.b.IsProductKeyValid(handle)
+	w.m.observe("IsProductKeyValid", start, ret)
+	return ret
+}
+
+func (w backend) SetCustomProxy(proxy string) turboactivate.HRESULT {
+	var start = time.Now()
+	var ret = w.b.SetCustomProxy(proxy)
+	w.m.observe("SetCustomProxy", start, ret)
+	return ret
+}
+
+func (w backend) TrialDaysRemaining(handle uint32, flags turboactivate.TAFlags) (uint32, turboactivate.HRESULT) {
+	var start = time.Now()
+	days, ret := w.b.TrialDaysRemaining(handle, flags)
+	w.m.observe("TrialDaysRemaining", start, ret)
+	return days, ret
+}
+
+func (w backend) UseTrial(handle uint32, flags turboactivate.TAFlags, extraData string) turboactivate.HRESULT {
+	var start = time.Now()
+	var ret = w.b.UseTrial(handle, flags, extraData)
+	w.m.observe("UseTrial", start, ret)
+	return ret
+}
+
+func (w backend) UseTrialVerifiedRequest(handle uint32, filename string, extraData string) turboactivate.HRESULT {
+	var start = time.Now()
+	var ret = w.b.UseTrialVerifiedRequest(handle, filename, extraData)
+	w.m.observe("UseTrialVerifiedRequest", start, ret)
+	return ret
+}
+
+func (w backend) UseTrialVerifiedFromFile(handle uint32, filename string, flags turboactivate.TAFlags) turboactivate.HRESULT {
+	var start = time.Now()
+	var ret = w.b.UseTrialVerifiedFromFile(handle, filename, flags)
+	w.m.observe("UseTrialVerifiedFromFile", start, ret)
+	return ret
+}
+
+func (w backend) ExtendTrial(handle uint32, flags turboactivate.TAFlags, trialExtension string) turboactivate.HRESULT {
+	var start = time.Now()
+	var ret = w.b.ExtendTrial(handle, flags, trialExtension)
+	w.m.observe("ExtendTrial", start, ret)
+	return ret
+}
+
+func (w backend) SetCustomActDataPath(handle uint32, directory string) turboactivate.HRESULT {
+	var start = time.Now()
+	var ret = w.b.SetCustomActDataPath(handle, directory)
+	w.m.observe("SetCustomActDataPath", start, ret)
+	return ret
+}
+
+func (w backend) SetTrialCallback(handle uint32, callback func(status uint32)) turboactivate.HRESULT {
+	var start = time.Now()
+	var ret = w.b.SetTrialCallback(handle, callback)
+	w.m.observe("SetTrialCallback", start, ret)
+	return ret
+}
+
+func (w backend) GetVersion() (uint32, uint32, uint32, uint32, turboactivate.HRESULT) {
+	var start = time.Now()
+	major, minor, build, revision, ret := w.b.GetVersion()
+	w.m.observe("GetVersion", start, ret)
+	return major, minor, build, revision, ret
+}
+
+func (w backend) Cleanup() turboactivate.HRESULT {
+	var start = time.Now()
+	var ret = w.b.Cleanup()
+	w.m.observe("Cleanup", start, ret)
+	return ret
+}
+
+func (w backend) BlackListKeys(handle uint32, keys []string) turboactivate.HRESULT {
+	var start = time.Now()
+	var ret = w.b.BlackListKeys(handle, keys)
+	w.m.observe("BlackListKeys", start, ret)
+	return ret
+}
@@ -0,0 +1,173 @@
+// Copyright 2018 wyDay, LLC. All rights reserved.
+
+// Package taprom exports Prometheus metrics about the TurboActivate library
+// calls and the licensing state of the product.
+//
+// The call metrics are recorded by wrapping the backend:
+//
+//	m, err := taprom.NewMetrics(prometheus.DefaultRegisterer, nil)
+//	ta, err := turboactivate.New(guid, m.Option())
+//
+// The licensing state gauges are updated from a Status() snapshot or
+// from the events of a Monitor:
+//
+//	mon := turboactivate.NewMonitor(ta, turboactivate.MonitorOptions{OnEvent: m.ObserveEvent})
+package taprom // import "golang.wyday.com/turboactivate/taprom"
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"golang.wyday.com/turboactivate"
+)
+
+// Metrics are the Prometheus metrics of one or more TurboActivate instances.
+//
+// The call metrics are:
+//
+//	turboactivate_calls_total{function, hresult}
+//	turboactivate_call_duration_seconds{function}
+//
+// where function is the name of the library function (e.g. "IsGenuineEx")
+// and hresult is the name of the HRESULT it returned (e.g. "TA_E_INET").
+//
+// The licensing state gauges are:
+//
+//	turboactivate_activated
+//	turboactivate_genuine_result{result}
+//	turboactivate_days_until_recheck
+//	turboactivate_in_grace_period
+//	turboactivate_trial_days_remaining
+//
+// turboactivate_genuine_result is 1 for the last IsGenuineResult
+// (e.g. result="Genuine") and 0 for the others.
+type Metrics struct {
+	calls    *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+
+	activated          prometheus.Gauge
+	genuineResult      *prometheus.GaugeVec
+	daysUntilRecheck   prometheus.Gauge
+	inGracePeriod      prometheus.Gauge
+	trialDaysRemaining prometheus.Gauge
+}
+
+// genuineResults are the values of the "result" label of turboactivate_genuine_result.
+var genuineResults = []turboactivate.IsGenuineResult{
+	turboactivate.IGRGenuine,
+	turboactivate.IGRGenuineFeaturesChanged,
+	turboactivate.IGRNotGenuine,
+	turboactivate.IGRNotGenuineInVM,
+	turboactivate.IGRInternetError,
+}
+
+// NewMetrics creates the metrics and registers them with reg. The constLabels
+// (e.g. {"product": "MyApp"}) are added to every metric, so the metrics of
+// several products can be registered with the same registerer.
+func NewMetrics(reg prometheus.Registerer, constLabels prometheus.Labels) (*Metrics, error) {
+	var m = &Metrics{
+		calls: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name:        "turboactivate_calls_total",
+			Help:        "Number of TurboActivate library calls by function and returned HRESULT.",
+			ConstLabels: constLabels,
+		}, []string{"function", "hresult"}),
+
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:        "turboactivate_call_duration_seconds",
+			Help:        "Duration of the TurboActivate library calls by function.",
+			ConstLabels: constLabels,
+			Buckets:     []float64{.001, .005, .025, .1, .5, 1, 2.5, 5, 10, 30, 60},
+		}, []string{"function"}),
+
+		activated: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name:        "turboactivate_activated",
+			Help:        "Whether the product is activated (1) or not (0).",
+			ConstLabels: constLabels,
+		}),
+
+		genuineResult: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name:        "turboactivate_genuine_result",
+			Help:        "The last result of IsGenuineEx (1 for the current result, 0 for the others).",
+			ConstLabels: constLabels,
+		}, []string{"result"}),
+
+		daysUntilRecheck: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name:        "turboactivate_days_until_recheck",
+			Help:        "Days until the activation must be re-verified with the LimeLM servers (or until the grace period ends).",
+			ConstLabels: constLabels,
+		}),
+
+		inGracePeriod: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name:        "turboactivate_in_grace_period",
+			Help:        "Whether the activation is in the grace period (1) or not (0).",
+			ConstLabels: constLabels,
+		}),
+
+		trialDaysRemaining: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name:        "turboactivate_trial_days_remaining",
+			Help:        "Days remaining in the trial.",
+			ConstLabels: constLabels,
+		}),
+	}
+
+	for _, c := range []prometheus.Collector{m.calls, m.duration, m.activated, m.genuineResult, m.daysUntilRecheck, m.inGracePeriod, m.trialDaysRemaining} {
+		if err := reg.Register(c); err != nil {
+			return nil, err
+		}
+	}
+
+	return m, nil
+}
+
+// Option returns the option that makes New wrap the backend with Wrap.
+func (m *Metrics) Option() turboactivate.Option {
+	return turboactivate.WithBackendWrapper(m.Wrap)
+}
+
+// ObserveStatus updates the licensing state gauges from a Status() snapshot.
+func (m *Metrics) ObserveStatus(st turboactivate.LicenseStatus) {
+	m.activated.Set(boolToFloat(st.Activated))
+	m.setGenuineResult(st.GenuineResult)
+	m.daysUntilRecheck.Set(float64(st.DaysUntilRecheck))
+	m.inGracePeriod.Set(boolToFloat(st.InGracePeriod))
+	m.trialDaysRemaining.Set(float64(st.TrialDaysLeft))
+}
+
+// ObserveEvent updates the licensing state gauges from a Monitor event.
+// The activated results (EventGenuine, EventFeaturesChanged,
+// EventInternetError and the grace events) set turboactivate_activated to 1,
+// and EventNotGenuine (without an error) sets it to 0. Pass it as
+// MonitorOptions.OnEvent (or call it with the events read from
+// Monitor.Events()).
+func (m *Metrics) ObserveEvent(ev turboactivate.MonitorEvent) {
+	m.setGenuineResult(ev.Result)
+
+	switch ev.Type {
+	case turboactivate.EventGenuine, turboactivate.EventFeaturesChanged, turboactivate.EventInternetError,
+		turboactivate.EventGraceEntered, turboactivate.EventGraceExpiring:
+		m.activated.Set(1)
+		m.daysUntilRecheck.Set(float64(ev.DaysRemaining))
+		m.inGracePeriod.Set(boolToFloat(ev.InGracePeriod))
+
+	case turboactivate.EventNotGenuine:
+		// an error (e.g. a timeout) doesn't tell whether the product is activated
+		if ev.Err == nil {
+			m.activated.Set(0)
+			m.daysUntilRecheck.Set(0)
+			m.inGracePeriod.Set(0)
+		}
+	}
+}
+
+func (m *Metrics) setGenuineResult(res turboactivate.IsGenuineResult) {
+	for _, r := range genuineResults {
+		m.genuineResult.WithLabelValues(r.String()).Set(boolToFloat(r == res))
+	}
+}
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,105 @@
+// Copyright 2018 wyDay, LLC. All rights reserved.
+
+package taprom_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"golang.wyday.com/turboactivate"
+	"golang.wyday.com/turboactivate/fake"
+	"golang.wyday.com/turboactivate/internal/tatest"
+	"golang.wyday.com/turboactivate/taprom"
+)
+
+// gauge returns the value of the metric (without labels) in reg.
+func gauge(t *testing.T, reg *prometheus.Registry, name string) float64 {
+	t.Helper()
+
+	families, err := reg.Gather()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, f := range families {
+		if f.GetName() == name {
+			return f.GetMetric()[0].GetGauge().GetValue()
+		}
+	}
+
+	t.Fatalf("no metric %s", name)
+	return 0
+}
+
+func TestCallMetrics(t *testing.T) {
+	var reg = prometheus.NewRegistry()
+
+	m, err := taprom.NewMetrics(reg, prometheus.Labels{"product": "test"})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ta := tatest.New(t, fake.New(), tatest.WithOptions(m.Option()))
+
+	ta.IsActivated()
+	ta.IsActivated()
+
+	if n, err := testutil.GatherAndCount(reg, "turboactivate_calls_total"); n == 0 || err != nil {
+		t.Fatalf("no calls were recorded: %v", err)
+	}
+
+	if _, err = taprom.NewMetrics(reg, prometheus.Labels{"product": "test"}); err == nil {
+		t.Error("registering the metrics twice didn't fail")
+	}
+
+	st, err := ta.Status(context.Background(), turboactivate.StatusOptions{})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m.ObserveStatus(st)
+
+	if v := gauge(t, reg, "turboactivate_activated"); v != 0 {
+		t.Errorf("turboactivate_activated = %v; want 0", v)
+	}
+}
+
+func TestObserveEventSetsActivated(t *testing.T) {
+	var reg = prometheus.NewRegistry()
+
+	m, err := taprom.NewMetrics(reg, nil)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m.ObserveEvent(turboactivate.MonitorEvent{Type: turboactivate.EventGenuine, Result: turboactivate.IGRGenuine, DaysRemaining: 30})
+
+	if v := gauge(t, reg, "turboactivate_activated"); v != 1 {
+		t.Errorf("turboactivate_activated after EventGenuine = %v; want 1", v)
+	}
+
+	if v := gauge(t, reg, "turboactivate_days_until_recheck"); v != 30 {
+		t.Errorf("turboactivate_days_until_recheck = %v; want 30", v)
+	}
+
+	// an error doesn't change whether the product is activated
+	m.ObserveEvent(turboactivate.MonitorEvent{Type: turboactivate.EventNotGenuine, Result: turboactivate.IGRNotGenuine, Err: errors.New("timeout")})
+
+	if v := gauge(t, reg, "turboactivate_activated"); v != 1 {
+		t.Errorf("turboactivate_activated after an error = %v; want 1", v)
+	}
+
+	m.ObserveEvent(turboactivate.MonitorEvent{Type: turboactivate.EventNotGenuine, Result: turboactivate.IGRNotGenuine})
+
+	if v := gauge(t, reg, "turboactivate_activated"); v != 0 {
+		t.Errorf("turboactivate_activated after EventNotGenuine = %v; want 0", v)
+	}
+}
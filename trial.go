@@ -0,0 +1,179 @@
+// Copyright 2018 wyDay, LLC. All rights reserved.
+
+package turboactivate // import "golang.wyday.com/turboactivate"
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strconv"
+)
+
+// TrialState is the state of a trial, derived from the HRESULTs of UseTrial()
+// and TrialDaysRemaining().
+type TrialState int
+
+const (
+	// TrialNotStarted means UseTrial() was never called (TA_E_MUST_USE_TRIAL).
+	TrialNotStarted TrialState = iota
+
+	// TrialActive means the trial has days remaining.
+	TrialActive
+
+	// TrialExpired means the trial has no days remaining (or TA_E_TRIAL_EXPIRED).
+	TrialExpired
+
+	// TrialCorrupted means the trial data was tampered with or corrupted
+	// (TA_E_TRIAL). TurboActivate then uses the oldest date possible, so the
+	// trial is usually expired too.
+	TrialCorrupted
+)
+
+// String returns the name of the state.
+func (s TrialState) String() string {
+	switch s {
+	case TrialNotStarted:
+		return "NotStarted"
+	case TrialActive:
+		return "Active"
+	case TrialExpired:
+		return "Expired"
+	case TrialCorrupted:
+		return "Corrupted"
+	default:
+		return "TrialState(" + strconv.Itoa(int(s)) + ")"
+	}
+}
+
+// ErrOfflineUnverifiedTrial is returned by the offline Trial functions
+// when the trial isn't a verified trial.
+var ErrOfflineUnverifiedTrial = errors.New("Only verified trials (TAVerifiedTrial) can be started offline")
+
+// Trial manages the trial of a product with a fixed set of flags, so they
+// don't have to be passed (and kept consistent) on every call.
+// Get it with TurboActivate.Trial().
+type Trial struct {
+	ta    *TurboActivate
+	flags TAFlags
+}
+
+// Trial returns the Trial for the flags, which must contain either TASystem
+// or TAUser (not both) and either TAVerifiedTrial or TAUnverifiedTrial (not
+// both). For example:
+//
+//	trial, err := ta.Trial(turboactivate.TASystem | turboactivate.TAVerifiedTrial)
+func (ta *TurboActivate) Trial(flags TAFlags) (*Trial, error) {
+	if err := validateFlags(flags, "Trial"); err != nil {
+		return nil, err
+	}
+
+	if flags&(TAVerifiedTrial|TAUnverifiedTrial) == 0 {
+		return nil, newTAError(TAEMustSpecifyTrialType, "Trial")
+	}
+
+	return &Trial{ta: ta, flags: flags}, nil
+}
+
+// Flags returns the flags of the trial.
+func (t *Trial) Flags() TAFlags {
+	return t.flags
+}
+
+// Verified returns whether the trial is a verified trial.
+func (t *Trial) Verified() bool {
+	return t.flags&TAVerifiedTrial != 0
+}
+
+// Start begins the trial the first time it's called (see UseTrial). Calling it
+// again validates the trial data hasn't been tampered with.
+// Returns TrialActive, TrialExpired or TrialCorrupted.
+func (t *Trial) Start(ctx context.Context, extraData string) (TrialState, error) {
+
+	ret, attempts, err := t.ta.invoke(ctx, func() HRESULT {
+		return t.ta.backend.UseTrial(t.ta.handle, t.flags, extraData)
+	})
+
+	if err != nil {
+		return TrialNotStarted, err
+	}
+
+	switch ret {
+	case 0x00: // TA_OK
+		return t.State()
+
+	case 0x1E: // TA_E_TRIAL_EXPIRED
+		return TrialExpired, nil
+
+	case 0x09: // TA_E_TRIAL
+		return TrialCorrupted, nil
+
+	default:
+		return TrialNotStarted, retryErr(taHresultToErr(ret, "UseTrial"), attempts)
+	}
+}
+
+// State returns the state of the trial without starting it.
+func (t *Trial) State() (TrialState, error) {
+	days, ret := t.ta.backend.TrialDaysRemaining(t.ta.handle, t.flags)
+
+	switch ret {
+	case 0x00: // TA_OK
+		if days == 0 {
+			return TrialExpired, nil
+		}
+
+		return TrialActive, nil
+
+	case 0x20: // TA_E_MUST_USE_TRIAL
+		return TrialNotStarted, nil
+
+	case 0x1E: // TA_E_TRIAL_EXPIRED
+		return TrialExpired, nil
+
+	case 0x09: // TA_E_TRIAL
+		return TrialCorrupted, nil
+
+	default:
+		return TrialNotStarted, taHresultToErr(ret, "TrialDaysRemaining")
+	}
+}
+
+// DaysRemaining gets the number of trial days remaining (see TrialDaysRemaining).
+// Start() must have been called at least once before.
+func (t *Trial) DaysRemaining() (uint32, error) {
+	return t.ta.TrialDaysRemaining(t.flags)
+}
+
+// Extend extends the trial using a trial extension created in LimeLM
+// (see ExtendTrial), and returns the new state of the trial.
+func (t *Trial) Extend(ctx context.Context, trialExtension string) (TrialState, error) {
+	if err := t.ta.ExtendTrialContext(ctx, trialExtension, t.flags); err != nil {
+		return TrialNotStarted, err
+	}
+
+	return t.State()
+}
+
+// OfflineRequest writes the offline verified trial request to w
+// (see UseTrialVerifiedRequest).
+func (t *Trial) OfflineRequest(w io.Writer, extraData string) error {
+	if !t.Verified() {
+		return ErrOfflineUnverifiedTrial
+	}
+
+	return t.ta.UseTrialVerifiedRequestTo(w, extraData)
+}
+
+// OfflineApply starts the verified trial with the response from LimeLM read
+// from r (see UseTrialVerifiedFromFile), and returns the state of the trial.
+func (t *Trial) OfflineApply(r io.Reader) (TrialState, error) {
+	if !t.Verified() {
+		return TrialNotStarted, ErrOfflineUnverifiedTrial
+	}
+
+	if err := t.ta.UseTrialVerifiedFrom(r, t.flags); err != nil {
+		return TrialNotStarted, err
+	}
+
+	return t.State()
+}
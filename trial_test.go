@@ -0,0 +1,134 @@
+// Copyright 2018 wyDay, LLC. All rights reserved.
+
+package turboactivate_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"golang.wyday.com/turboactivate"
+	"golang.wyday.com/turboactivate/fake"
+)
+
+func TestTrial(t *testing.T) {
+	b := fake.New()
+	b.AddProduct(testGUID, fake.Product{TrialDays: 10, TrialExtensions: map[string]uint32{"EXT": 5}})
+
+	var clock = fake.NewManualClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	b.SetClock(clock.Now)
+
+	ta, err := turboactivate.New(testGUID, turboactivate.WithBackend(b))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer ta.Close()
+
+	trial, err := ta.Trial(turboactivate.TAUser | turboactivate.TAVerifiedTrial)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if state, err := trial.State(); state != turboactivate.TrialNotStarted || err != nil {
+		t.Errorf("State() before Start() = %v, %v; want NotStarted", state, err)
+	}
+
+	if state, err := trial.Start(context.Background(), ""); state != turboactivate.TrialActive || err != nil {
+		t.Fatalf("Start() = %v, %v; want Active", state, err)
+	}
+
+	if days, err := trial.DaysRemaining(); days != 10 || err != nil {
+		t.Errorf("DaysRemaining() = %d, %v; want 10", days, err)
+	}
+
+	clock.AdvanceDays(11)
+
+	if state, err := trial.State(); state != turboactivate.TrialExpired || err != nil {
+		t.Errorf("State() after the trial = %v, %v; want Expired", state, err)
+	}
+
+	if state, err := trial.Extend(context.Background(), "EXT"); state != turboactivate.TrialActive || err != nil {
+		t.Errorf("Extend() = %v, %v; want Active", state, err)
+	}
+
+	if _, err := trial.Extend(context.Background(), "EXT"); !errors.Is(err, turboactivate.ErrTrialExtUsed) {
+		t.Errorf("Extend() twice = %v; want ErrTrialExtUsed", err)
+	}
+
+	// turning the clock back is detected
+	clock.AdvanceDays(-30)
+
+	if state, err := trial.Start(context.Background(), ""); state != turboactivate.TrialCorrupted || err != nil {
+		t.Errorf("Start() after turning the clock back = %v, %v; want Corrupted", state, err)
+	}
+}
+
+func TestTrialFlags(t *testing.T) {
+	ta, err := turboactivate.New(testGUID, turboactivate.WithBackend(fake.New()))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer ta.Close()
+
+	for flags, want := range map[turboactivate.TAFlags]error{
+		turboactivate.TAUser:                          turboactivate.ErrMustSpecifyTrialType,
+		turboactivate.TAVerifiedTrial:                 turboactivate.ErrInvalidFlags,
+		turboactivate.TASystem | turboactivate.TAUser: turboactivate.ErrInvalidFlags,
+		turboactivate.TAUser | turboactivate.TAVerifiedTrial | turboactivate.TAUnverifiedTrial: turboactivate.ErrMustSpecifyTrialType,
+	} {
+		if _, err := ta.Trial(flags); !errors.Is(err, want) {
+			t.Errorf("Trial(%#x) = %v; want %v", uint32(flags), err, want)
+		}
+	}
+
+	trial, err := ta.Trial(turboactivate.TASystem | turboactivate.TAUnverifiedTrial)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if trial.Verified() || trial.Flags() != turboactivate.TASystem|turboactivate.TAUnverifiedTrial {
+		t.Errorf("Verified() = %v, Flags() = %#x", trial.Verified(), uint32(trial.Flags()))
+	}
+
+	if err = trial.OfflineRequest(&bytes.Buffer{}, ""); !errors.Is(err, turboactivate.ErrOfflineUnverifiedTrial) {
+		t.Errorf("OfflineRequest() of an unverified trial = %v; want ErrOfflineUnverifiedTrial", err)
+	}
+}
+
+func TestTrialOffline(t *testing.T) {
+	b := fake.New()
+	b.AddProduct(testGUID, fake.Product{TrialDays: 10})
+	b.SetOnline(false)
+
+	ta, err := turboactivate.New(testGUID, turboactivate.WithBackend(b))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer ta.Close()
+
+	trial, err := ta.Trial(turboactivate.TAUser | turboactivate.TAVerifiedTrial)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var req bytes.Buffer
+
+	if err = trial.OfflineRequest(&req, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if state, err := trial.OfflineApply(&req); state != turboactivate.TrialActive || err != nil {
+		t.Errorf("OfflineApply() = %v, %v; want Active", state, err)
+	}
+}
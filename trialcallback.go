@@ -0,0 +1,197 @@
+// Copyright 2018 wyDay, LLC. All rights reserved.
+
+package turboactivate // import "golang.wyday.com/turboactivate"
+
+import (
+	"strconv"
+	"sync"
+	"time"
+)
+
+// TrialEventType is the type of a TrialEvent.
+type TrialEventType uint32
+
+const (
+	// TrialEventExpired means the verified trial has expired (TA_CB_EXPIRED).
+	TrialEventExpired TrialEventType = 0x00
+
+	// TrialEventExpiredFraud means the verified trial has expired because the
+	// trial data was tampered with, e.g. the clock was turned back (TA_CB_EXPIRED_FRAUD).
+	TrialEventExpiredFraud TrialEventType = 0x01
+
+	// TrialEventExtensionApplied means ExtendTrial() extended the trial.
+	// It's raised by this package, not by the TurboActivate library.
+	TrialEventExtensionApplied TrialEventType = 0x100
+)
+
+// String returns the name of the event type.
+func (t TrialEventType) String() string {
+	switch t {
+	case TrialEventExpired:
+		return "Expired"
+	case TrialEventExpiredFraud:
+		return "ExpiredFraud"
+	case TrialEventExtensionApplied:
+		return "ExtensionApplied"
+	default:
+		return "TrialEventType(" + strconv.FormatUint(uint64(t), 10) + ")"
+	}
+}
+
+// TrialEvent is passed to the function set with SetTrialCallback.
+type TrialEvent struct {
+	Type TrialEventType
+
+	// Time is when the event was received.
+	Time time.Time
+}
+
+// trialCallbacks has the function set with SetTrialCallback for each handle
+// of each backend.
+var (
+	trialCallbacksMu sync.Mutex
+	trialCallbacks   = map[handleKey]func(TrialEvent){}
+)
+
+// nativeTrialCallbacks has the trial callback set on the library backends
+// for each handle. Each handle of each library gets a slot, which is passed
+// to the library as the user defined pointer of TA_SetTrialCallback, so no
+// Go pointers are passed to C.
+var (
+	nativeTrialCallbacksMu sync.Mutex
+	nativeTrialSlots       = map[handleKey]uintptr{}
+	nativeTrialCallbacks   = map[uintptr]func(status uint32){}
+	nativeTrialNextSlot    uintptr
+)
+
+// setNativeTrialCallback sets the callback of the handle of the library
+// backend and returns the handle's slot.
+func setNativeTrialCallback(backend Backend, handle uint32, callback func(status uint32)) uintptr {
+	var key = keyOf(backend, handle)
+
+	nativeTrialCallbacksMu.Lock()
+	defer nativeTrialCallbacksMu.Unlock()
+
+	slot, ok := nativeTrialSlots[key]
+
+	if !ok {
+		nativeTrialNextSlot++
+		slot = nativeTrialNextSlot
+		nativeTrialSlots[key] = slot
+	}
+
+	if callback == nil {
+		delete(nativeTrialCallbacks, slot)
+	} else {
+		nativeTrialCallbacks[slot] = callback
+	}
+
+	return slot
+}
+
+// nativeTrialCallback is called by the library when the trial of the handle
+// with the slot changes.
+func nativeTrialCallback(status uint32, slot uintptr) {
+	nativeTrialCallbacksMu.Lock()
+	var callback = nativeTrialCallbacks[slot]
+	nativeTrialCallbacksMu.Unlock()
+
+	if callback != nil {
+		callback(status)
+	}
+}
+
+// resetNativeTrialCallbacks forgets the callbacks of every handle of the
+// library backend (after TA_Cleanup).
+func resetNativeTrialCallbacks(backend Backend) {
+	var id = backendOf(backend)
+
+	nativeTrialCallbacksMu.Lock()
+	defer nativeTrialCallbacksMu.Unlock()
+
+	for key, slot := range nativeTrialSlots {
+		if key.backend == id {
+			delete(nativeTrialSlots, key)
+			delete(nativeTrialCallbacks, slot)
+		}
+	}
+}
+
+// resetTrialCallbacks forgets the SetTrialCallback functions of every handle
+// of the backend (see Cleanup).
+func resetTrialCallbacks(backend Backend) {
+	var id = backendOf(backend)
+
+	trialCallbacksMu.Lock()
+	defer trialCallbacksMu.Unlock()
+
+	for key := range trialCallbacks {
+		if key.backend == id {
+			delete(trialCallbacks, key)
+		}
+	}
+}
+
+// raiseTrialEvent calls the trial callback of the handle (if any) on a new goroutine.
+func raiseTrialEvent(key handleKey, typ TrialEventType) {
+	trialCallbacksMu.Lock()
+	var callback = trialCallbacks[key]
+	trialCallbacksMu.Unlock()
+
+	if callback != nil {
+		go callback(TrialEvent{Type: typ, Time: time.Now()})
+	}
+}
+
+// SetTrialCallback sets the function called when the verified trial expires
+// (TrialEventExpired), expires because it was tampered with
+// (TrialEventExpiredFraud), or is extended by ExtendTrial()
+// (TrialEventExtensionApplied). Pass nil to stop the notifications.
+//
+// The TurboActivate library checks the trial while UseTrial() runs and in the
+// background after it, so use this instead of polling TrialDaysRemaining().
+// The callback is called on a new goroutine for each event, so it can call any
+// TurboActivate function. There's one callback per VersionGUID (of each
+// backend): setting it on one TurboActivate instance replaces the one set on
+// the others.
+func (ta *TurboActivate) SetTrialCallback(callback func(TrialEvent)) error {
+
+	if ta.Closed() {
+		return &ClosedError{Func: "SetTrialCallback"}
+	}
+
+	var key = ta.state.key
+
+	trialCallbacksMu.Lock()
+
+	if callback == nil {
+		delete(trialCallbacks, key)
+	} else {
+		trialCallbacks[key] = callback
+	}
+
+	trialCallbacksMu.Unlock()
+
+	var native func(status uint32)
+
+	if callback != nil {
+		native = func(status uint32) {
+			raiseTrialEvent(key, TrialEventType(status))
+		}
+	}
+
+	var ret = ta.backend.SetTrialCallback(ta.handle, native)
+
+	// TA_OK
+	if ret == 0x00 {
+		return nil
+	}
+
+	if callback != nil {
+		trialCallbacksMu.Lock()
+		delete(trialCallbacks, key)
+		trialCallbacksMu.Unlock()
+	}
+
+	return taHresultToErr(ret, "SetTrialCallback")
+}
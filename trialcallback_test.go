@@ -0,0 +1,123 @@
+// Copyright 2018 wyDay, LLC. All rights reserved.
+
+package turboactivate_test
+
+import (
+	"testing"
+	"time"
+
+	"golang.wyday.com/turboactivate"
+	"golang.wyday.com/turboactivate/fake"
+)
+
+// waitEvent returns the next event from events, or fails the test.
+func waitEvent(t *testing.T, events <-chan turboactivate.TrialEvent) turboactivate.TrialEvent {
+	t.Helper()
+
+	select {
+	case ev := <-events:
+		return ev
+	case <-time.After(5 * time.Second):
+		t.Fatal("no trial event")
+		return turboactivate.TrialEvent{}
+	}
+}
+
+// noEvent fails the test if events receives an event soon.
+func noEvent(t *testing.T, events <-chan turboactivate.TrialEvent) {
+	t.Helper()
+
+	select {
+	case ev := <-events:
+		t.Fatalf("unexpected trial event %v", ev.Type)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestTrialCallback(t *testing.T) {
+	b := fake.New()
+	b.AddProduct(testGUID, fake.Product{TrialDays: 10, TrialExtensions: map[string]uint32{"EXT": 5}})
+
+	ta, err := turboactivate.New(testGUID, turboactivate.WithBackend(b))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer ta.Close()
+
+	var events = make(chan turboactivate.TrialEvent, 4)
+
+	if err = ta.SetTrialCallback(func(ev turboactivate.TrialEvent) { events <- ev }); err != nil {
+		t.Fatal(err)
+	}
+
+	b.FireTrialCallback(testGUID, 0x01)
+
+	if ev := waitEvent(t, events); ev.Type != turboactivate.TrialEventExpiredFraud {
+		t.Errorf("event = %v; want ExpiredFraud", ev.Type)
+	}
+
+	if _, err = ta.UseTrial(turboactivate.TAUser|turboactivate.TAVerifiedTrial, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = ta.ExtendTrial("EXT", turboactivate.TAUser|turboactivate.TAVerifiedTrial); err != nil {
+		t.Fatal(err)
+	}
+
+	if ev := waitEvent(t, events); ev.Type != turboactivate.TrialEventExtensionApplied {
+		t.Errorf("event = %v; want ExtensionApplied", ev.Type)
+	}
+
+	if err = ta.SetTrialCallback(nil); err != nil {
+		t.Fatal(err)
+	}
+
+	b.FireTrialCallback(testGUID, 0x00)
+	noEvent(t, events)
+}
+
+func TestTrialCallbackOfDifferentBackends(t *testing.T) {
+	b1 := fake.New()
+	b2 := fake.New()
+
+	ta1, err := turboactivate.New(testGUID, turboactivate.WithBackend(b1))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer ta1.Close()
+
+	ta2, err := turboactivate.New(testGUID2, turboactivate.WithBackend(b2))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer ta2.Close()
+
+	var events1 = make(chan turboactivate.TrialEvent, 4)
+	var events2 = make(chan turboactivate.TrialEvent, 4)
+
+	// both fake backends hand out handle 1
+	ta1.SetTrialCallback(func(ev turboactivate.TrialEvent) { events1 <- ev })
+	ta2.SetTrialCallback(func(ev turboactivate.TrialEvent) { events2 <- ev })
+
+	b1.FireTrialCallback(testGUID, 0x00)
+
+	if ev := waitEvent(t, events1); ev.Type != turboactivate.TrialEventExpired {
+		t.Errorf("event = %v; want Expired", ev.Type)
+	}
+
+	noEvent(t, events2)
+
+	b2.FireTrialCallback(testGUID2, 0x01)
+
+	if ev := waitEvent(t, events2); ev.Type != turboactivate.TrialEventExpiredFraud {
+		t.Errorf("event = %v; want ExpiredFraud", ev.Type)
+	}
+
+	noEvent(t, events1)
+}
@@ -2,23 +2,29 @@
 
 package turboactivate // import "golang.wyday.com/turboactivate"
 
-/*
-#cgo CFLAGS: -I .
-#cgo LDFLAGS: -L . -L .. -lTurboActivate
-
-#include <stdlib.h>
-#include "TurboActivate.h"
-*/
-import "C"
 import (
-	"errors"
-	"strconv"
-	"unsafe"
+	"context"
+	"io/fs"
+	"time"
 )
 
 // The TurboActivate object.
+//
+// A TurboActivate object is safe for concurrent use by multiple goroutines.
+// The calls into the TurboActivate library are serialized per handle (that
+// is, per VersionGUID), so concurrent calls wait for each other.
+//
+// Create it with New (or one of the NewTurboActivate functions) and call
+// Close() when you're done with it.
 type TurboActivate struct {
-	handle C.uint32_t
+	handle       uint32
+	backend      Backend
+	defaultFlags TAFlags
+	retry        *RetryPolicy
+	state        *handleState
+
+	// inflight is held while a Context function's library call is running.
+	inflight chan struct{}
 }
 
 // IsGenuineResult is the result from the IsGenuine() and IsGenuinEx() functions
@@ -81,163 +87,137 @@ var (
 	TAHasNotExpired TADateCheckFlags = 1
 )
 
-func taHresultToErr(ret C.HRESULT, funcName string) error {
-	switch ret {
-	case 0x01: // TA_FAIL
-		return errors.New(funcName + " general failure")
-	case 0x02: // TA_E_PKEY
-		return errors.New("The product key is invalid or there's no product key")
-	case 0x03: // TA_E_ACTIVATE
-		return errors.New("The product needs to be activated")
-	case 0x04: // TA_E_INET
-		// More information here: https://wyday.com/limelm/help/faq/#internet-error
-		return errors.New("Connection to the servers failed")
-	case 0x05: // TA_E_INUSE
-		return errors.New("The product key has already been activated with the maximum number of computers")
-	case 0x06: // TA_E_REVOKED
-		return errors.New("The product key has been revoked")
-	case 0x09: // TA_E_TRIAL
-		return errors.New("The trial data has been corrupted, using the oldest date possible")
-	case 0x0B: // TA_E_COM
-		return errors.New("CoInitializeEx failed. Re-enable Windows Management Instrumentation (WMI) service. Contact your system admin for more information")
-	case 0x0C: // TA_E_TRIAL_EUSED
-		return errors.New("The trial extension has already been used")
-	case 0x0D: // TA_E_EXPIRED
-		return errors.New("The activation has expired or the system time has been tampered with. Ensure your time, timezone, and date settings are correct. After fixing them restart your computer")
-	case 0x0F: // TA_E_PERMISSION
-		return errors.New("Insufficient system permission. Either start your process as an admin / elevated user or call the function again with the TA_USER flag")
-	case 0x10: // TA_E_INVALID_FLAGS
-		return errors.New("The flags you passed to the function were invalid (or missing). Flags like \"TA_SYSTEM\" and \"TA_USER\" are mutually exclusive -- you can only use one or the other")
-	case 0x11: // TA_E_IN_VM
-		return errors.New("The function failed because this instance of your program is running inside a virtual machine / hypervisor and you've prevented the function from running inside a VM")
-	case 0x12: // TA_E_EDATA_LONG
-		return errors.New("The \"extra data\" was too long. You're limited to 255 UTF-8 characters. Or, on Windows, a Unicode string that will convert into 255 UTF-8 characters or less")
-	case 0x13: // TA_E_INVALID_ARGS
-		return errors.New("The arguments passed to the function are invalid. Double check your logic")
-	case 0x14: // TA_E_KEY_FOR_TURBOFLOAT
-		return errors.New("The product key used is for TurboFloat Server, not TurboActivate")
-	case 0x18: // TA_E_NO_MORE_DEACTIVATIONS
-		return errors.New("No more deactivations are allowed for the product key. This product is still activated on this computer")
-	case 0x19: // TA_E_ACCOUNT_CANCELED
-		return errors.New("Can't activate because the LimeLM account is cancelled")
-	case 0x1A: // TA_E_ALREADY_ACTIVATED
-		return errors.New("You can't use a product key because your app is already activated with a product key. To use a new product key, then first deactivate using either the Deactivate() or DeactivationRequestToFile()")
-	case 0x1B: // TA_E_INVALID_HANDLE
-		return errors.New("The handle is not valid. You must set a valid VersionGUID when constructing TurboActivate object")
-	case 0x1C: // TA_E_ENABLE_NETWORK_ADAPTERS
-		// More information here: https://wyday.com/limelm/help/faq/#disabled-adapters
-		return errors.New("There are network adapters on the system that are disabled and TurboActivate couldn't read their hardware properties (even after trying and failing to enable the adapters automatically). Enable the network adapters, re-run the function, and TurboActivate will be able to \"remember\" the adapters even if the adapters are disabled in the future")
-	case 0x1D: // TA_E_ALREADY_VERIFIED_TRIAL
-		return errors.New("The trial is already a verified trial. You need to use the \"TA_VERIFIED_TRIAL\" flag. Can't \"downgrade\" a verified trial to an unverified trial")
-	case 0x1E: // TA_E_TRIAL_EXPIRED
-		return errors.New("The verified trial has expired. You must request a trial extension from the company")
-	case 0x1F: // TA_E_MUST_SPECIFY_TRIAL_TYPE
-		return errors.New("You must specify the trial type (TA_UNVERIFIED_TRIAL or TA_VERIFIED_TRIAL). And you can't use both flags. Choose one or the other. We recommend TA_VERIFIED_TRIAL")
-	case 0x20: // TA_E_MUST_USE_TRIAL
-		return errors.New("You must call TA_UseTrial() before you can get the number of trial days remaining")
-	case 0x21: // TA_E_NO_MORE_TRIALS_ALLOWED
-		return errors.New("In the LimeLM account either the trial days is set to 0, OR the account is set to not auto-upgrade and thus no more verified trials can be made")
-	case 0x22: // TA_E_BROKEN_WMI
-		return errors.New("The WMI repository on the computer is broken. To fix the WMI repository see the instructions here: https://wyday.com/limelm/help/faq/#fix-broken-wmi")
-	case 0x23: // TA_E_INET_TIMEOUT
-		return errors.New("The connection to the server timed out because a long period of time elapsed since the last data was sent or received")
-	case 0x24: // TA_E_INET_TLS
-		// More information here: https://wyday.com/limelm/help/faq/#internet-error
-		return errors.New("The secure connection to the activation servers failed due to a TLS or certificate error. More information here: https://wyday.com/limelm/help/faq/#internet-error")
-	default:
-		// Make sure you're using the latest turboactivate.go, we occassionally add new error codes
-		// and you need latest version of this file to get a detailed description of the error.
+// taHresultToErr converts the HRESULT returned by funcName into a *TAError.
+// The returned error can be matched with errors.Is against the Err* values.
+func taHresultToErr(ret HRESULT, funcName string) error {
+	if ret == hrClosed {
+		return &ClosedError{Func: funcName}
+	}
 
-		// More information about upgrading here: https://wyday.com/limelm/help/faq/#update-libs
+	return newTAError(ret, funcName)
+}
+
+// NewTurboActivate creates a new TurboActivate instance for the provided GUID
+func NewTurboActivate(taGUID string, pdetsFilename string) (*TurboActivate, error) {
 
-		// You can also view error directly from the source: TurboActivate.h
-		return errors.New(funcName + " failed with an unknown error code: " + strconv.FormatUint(uint64(ret), 10))
+	if defaultBackend == nil {
+		return nil, defaultBackendErr
 	}
+
+	return NewTurboActivateWithBackend(defaultBackend, taGUID, pdetsFilename)
 }
 
-// NewTurboActivate creates a new TurboActivate instance for the provided GUID
-func NewTurboActivate(taGUID string, pdetsFilename string) (TurboActivate, error) {
+// NewTurboActivateWithBackend creates a new TurboActivate instance for the provided GUID
+// that calls the TurboActivate functions on the passed in backend instead of the
+// native TurboActivate library.
+func NewTurboActivateWithBackend(backend Backend, taGUID string, pdetsFilename string) (*TurboActivate, error) {
+
+	backend = serialize(backend)
 
 	// Load the TurboActivate.dat file if a path was passed in.
 	if pdetsFilename != "" {
-		var nativeFilename = getTAStrPtr(pdetsFilename)
+		if err := pdetsResultToErr(backend.PDetsFromPath(pdetsFilename), "PDetsFromPath"); err != nil {
+			return nil, err
+		}
+	}
 
-		var ret = C.TA_PDetsFromPath(nativeFilename)
+	return newTurboActivate(backend, taGUID), nil
+}
 
-		C.free(unsafe.Pointer(nativeFilename))
+// NewTurboActivateFromBytes creates a new TurboActivate instance for the provided GUID
+// and loads the product details from the contents of the TurboActivate.dat file.
+// Use this to embed TurboActivate.dat in your app (e.g. with go:embed) instead
+// of shipping it next to your app.
+func NewTurboActivateFromBytes(taGUID string, pdets []byte) (*TurboActivate, error) {
 
-		// ret != TA_OK && ret != TA_FAIL
-		if ret != 0x00 && ret != 0x01 {
-			return TurboActivate{}, errors.New("The TurboActivate.dat file failed to load")
-		}
+	if defaultBackend == nil {
+		return nil, defaultBackendErr
 	}
 
-	var nativeTaGUID = getTAStrPtr(taGUID)
+	var backend = serialize(defaultBackend)
 
-	var handl C.uint32_t = C.TA_GetHandle(nativeTaGUID)
-
-	C.free(unsafe.Pointer(nativeTaGUID))
+	if err := pdetsResultToErr(backend.PDetsFromByteArray(pdets), "PDetsFromByteArray"); err != nil {
+		return nil, err
+	}
 
-	return TurboActivate{
-		handle: handl,
-	}, nil
+	return newTurboActivate(backend, taGUID), nil
 }
 
-// Activate activates the product on this computer. You must call "CheckAndSavePKey()
-// with a valid product key or have used the TurboActivate Wizard sometime before
-// calling this function.
-// extraData: Extra data to pass to the LimeLM servers that will be visible for you to see and use.
-//            Maximum size is 255 UTF-8 characters
-// Returns nil on no error.
-func (ta *TurboActivate) Activate(extraData string) error {
-
-	var ret C.HRESULT
+// NewTurboActivateFromFS creates a new TurboActivate instance for the provided GUID
+// and loads the product details from the named TurboActivate.dat file in fsys.
+// For example:
+//
+//	//go:embed TurboActivate.dat
+//	var pdets embed.FS
+//
+//	ta, err := turboactivate.NewTurboActivateFromFS(guid, pdets, "TurboActivate.dat")
+func NewTurboActivateFromFS(taGUID string, fsys fs.FS, name string) (*TurboActivate, error) {
 
-	if extraData == "" {
-		ret = C.TA_Activate(ta.handle, (*C.ACTIVATE_OPTIONS)(nil))
-	} else {
+	data, err := fs.ReadFile(fsys, name)
 
-		var actOptions C.ACTIVATE_OPTIONS
-		actOptions.nLength = C.uint32_t(unsafe.Sizeof(actOptions))
-		actOptions.sExtraData = (C.STRCTYPE)(getTAStrPtr(extraData))
+	if err != nil {
+		return nil, err
+	}
 
-		ret = C.TA_Activate(ta.handle, (*C.ACTIVATE_OPTIONS)(unsafe.Pointer(&actOptions)))
+	return NewTurboActivateFromBytes(taGUID, data)
+}
 
-		C.free(unsafe.Pointer(actOptions.sExtraData))
-	}
+// pdetsResultToErr converts the result of loading the TurboActivate.dat file into an error.
+func pdetsResultToErr(ret HRESULT, funcName string) error {
 
-	// TA_OK
-	if ret == 0x00 {
-		return nil
+	// ret != TA_OK && ret != TA_FAIL
+	if ret != 0x00 && ret != 0x01 {
+		return &TAError{
+			Code:    ret,
+			Func:    funcName,
+			Message: "The TurboActivate.dat file failed to load",
+		}
 	}
 
-	return taHresultToErr(ret, "Activate")
+	return nil
 }
 
-// ActivationRequestToFile gets the "activation request" file for offline activation.
-// You must call CheckAndSavePKey() with a valid product key or have used the
-// TurboActivate wizard sometime before calling this function.
-func (ta *TurboActivate) ActivationRequestToFile(filename string, extraData string) error {
+func newTurboActivate(backend Backend, taGUID string) *TurboActivate {
+	backend = serialize(backend)
 
-	var nativeFilename = getTAStrPtr(filename)
+	var state = &handleState{
+		handle:      backend.GetHandle(taGUID),
+		versionGUID: taGUID,
+		backend:     backend,
+	}
 
-	var ret C.HRESULT
+	state.key = keyOf(backend, state.handle)
 
-	if extraData == "" {
-		ret = C.TA_ActivationRequestToFile(ta.handle, nativeFilename, (*C.ACTIVATE_OPTIONS)(nil))
-	} else {
+	var ta = &TurboActivate{
+		handle:   state.handle,
+		backend:  closableBackend{b: backend, closed: &state.closed},
+		state:    state,
+		inflight: make(chan struct{}, 1),
+	}
 
-		var actOptions C.ACTIVATE_OPTIONS
+	if ta.handle != 0 {
+		ta.track()
+	}
 
-		actOptions.nLength = C.uint32_t(unsafe.Sizeof(actOptions))
-		actOptions.sExtraData = (C.STRCTYPE)(getTAStrPtr(extraData))
+	return ta
+}
 
-		ret = C.TA_ActivationRequestToFile(ta.handle, nativeFilename, (*C.ACTIVATE_OPTIONS)(unsafe.Pointer(&actOptions)))
+// Activate activates the product on this computer. You must call "CheckAndSavePKey()
+// with a valid product key or have used the TurboActivate Wizard sometime before
+// calling this function.
+// extraData: Extra data to pass to the LimeLM servers that will be visible for you to see and use.
+//            Maximum size is 255 UTF-8 characters
+// Returns nil on no error.
+func (ta *TurboActivate) Activate(extraData string) error {
+	return ta.ActivateContext(context.Background(), extraData)
+}
 
-		C.free(unsafe.Pointer(actOptions.sExtraData))
-	}
+// ActivationRequestToFile gets the "activation request" file for offline activation.
+// You must call CheckAndSavePKey() with a valid product key or have used the
+// TurboActivate wizard sometime before calling this function.
+func (ta *TurboActivate) ActivationRequestToFile(filename string, extraData string) error {
 
-	C.free(unsafe.Pointer(nativeFilename))
+	var ret = ta.backend.ActivationRequestToFile(ta.handle, filename, extraData)
 
 	// TA_OK
 	if ret == 0x00 {
@@ -251,11 +231,7 @@ func (ta *TurboActivate) ActivationRequestToFile(filename string, extraData stri
 // for offline activations.
 func (ta *TurboActivate) ActivateFromFile(filename string) error {
 
-	var nativeFilename = getTAStrPtr(filename)
-
-	var ret C.HRESULT = C.TA_ActivateFromFile(ta.handle, nativeFilename)
-
-	C.free(unsafe.Pointer(nativeFilename))
+	var ret = ta.backend.ActivateFromFile(ta.handle, filename)
 
 	// TA_OK
 	if ret == 0x00 {
@@ -270,11 +246,7 @@ func (ta *TurboActivate) ActivateFromFile(filename string) error {
 // product key to a particular machine.
 func (ta *TurboActivate) CheckAndSavePKey(productKey string, flags TAFlags) (bool, error) {
 
-	var nativeProductKey = getTAStrPtr(productKey)
-
-	var ret C.HRESULT = C.TA_CheckAndSavePKey(ta.handle, nativeProductKey, C.uint32_t(flags))
-
-	C.free(unsafe.Pointer(nativeProductKey))
+	var ret = ta.backend.CheckAndSavePKey(ta.handle, productKey, flags)
 
 	switch ret {
 	case 0x00: // TA_OK
@@ -290,40 +262,13 @@ func (ta *TurboActivate) CheckAndSavePKey(productKey string, flags TAFlags) (boo
 
 // Deactivate deactivates the product on this computer.
 func (ta *TurboActivate) Deactivate(eraseProductKey bool) error {
-
-	var erasePK C.char
-
-	if eraseProductKey {
-		erasePK = 1
-	} else {
-		erasePK = 0
-	}
-
-	var ret C.HRESULT = C.TA_Deactivate(ta.handle, erasePK)
-
-	// TA_OK
-	if ret == 0x00 {
-		return nil
-	}
-
-	return taHresultToErr(ret, "Deactivate")
+	return ta.DeactivateContext(context.Background(), eraseProductKey)
 }
 
 // DeactivationRequestToFile get the "deactivation request" file for offline deactivation.
 func (ta *TurboActivate) DeactivationRequestToFile(filename string, eraseProductKey bool) error {
-	var erasePK C.char
-
-	if eraseProductKey {
-		erasePK = 1
-	} else {
-		erasePK = 0
-	}
-
-	var nativeFilename = getTAStrPtr(filename)
-
-	var ret C.HRESULT = C.TA_DeactivationRequestToFile(ta.handle, nativeFilename, erasePK)
 
-	C.free(unsafe.Pointer(nativeFilename))
+	var ret = ta.backend.DeactivationRequestToFile(ta.handle, filename, eraseProductKey)
 
 	// TA_OK
 	if ret == 0x00 {
@@ -337,23 +282,13 @@ func (ta *TurboActivate) DeactivationRequestToFile(filename string, eraseProduct
 // Returns the extra data if it exists, otherwise it returns an empty string.
 func (ta *TurboActivate) GetExtraData() (string, error) {
 
-	var ret C.HRESULT = C.TA_GetExtraData(ta.handle, nil, 0)
-
-	var nativeExtraData = getTAStrBufferPtr(C.size_t(ret))
-
-	ret = C.TA_GetExtraData(ta.handle, nativeExtraData, C.int(ret))
+	extraData, ret := ta.backend.GetExtraData(ta.handle)
 
 	// TA_OK
 	if ret == 0x00 {
-		var featureValue = stringFromTAStrPtr(nativeExtraData)
-
-		C.free(unsafe.Pointer(nativeExtraData))
-
-		return featureValue, nil
+		return extraData, nil
 	}
 
-	C.free(unsafe.Pointer(nativeExtraData))
-
 	return "", taHresultToErr(ret, "GetExtraData")
 }
 
@@ -361,27 +296,13 @@ func (ta *TurboActivate) GetExtraData() (string, error) {
 // More information on custom license fields: https://wyday.com/limelm/help/license-features/
 func (ta *TurboActivate) GetFeatureValue(featureName string) (string, error) {
 
-	var nativeFeatureName = getTAStrPtr(featureName)
-
-	var ret C.HRESULT = C.TA_GetFeatureValue(ta.handle, nativeFeatureName, nil, 0)
-
-	var nativeFeatureValue = getTAStrBufferPtr(C.size_t(ret))
-
-	ret = C.TA_GetFeatureValue(ta.handle, nativeFeatureName, nativeFeatureValue, C.int(ret))
-
-	C.free(unsafe.Pointer(nativeFeatureName))
+	featureValue, ret := ta.backend.GetFeatureValue(ta.handle, featureName)
 
 	// TA_OK
 	if ret == 0x00 {
-		var featureValue = stringFromTAStrPtr(nativeFeatureValue)
-
-		C.free(unsafe.Pointer(nativeFeatureValue))
-
 		return featureValue, nil
 	}
 
-	C.free(unsafe.Pointer(nativeFeatureValue))
-
 	return "", taHresultToErr(ret, "GetFeatureValue")
 }
 
@@ -389,28 +310,22 @@ func (ta *TurboActivate) GetFeatureValue(featureName string) (string, error) {
 // key is valid simply call IsProductKeyValid(). If you want to check if your app
 // is locked to the computer then call IsGenuineEx() or IsActivated().
 func (ta *TurboActivate) GetPKey() (string, error) {
-	var nativePkey = getTAStrBufferPtr(35)
 
-	var ret C.HRESULT = C.TA_GetPKey(ta.handle, nativePkey, 35)
+	pkey, ret := ta.backend.GetPKey(ta.handle)
 
 	// TA_OK
 	if ret == 0x00 {
-		var pkey = stringFromTAStrPtr(nativePkey)
-
-		C.free(unsafe.Pointer(nativePkey))
-
 		return pkey, nil
 	}
 
-	C.free(unsafe.Pointer(nativePkey))
-
 	return "", taHresultToErr(ret, "GetPKey")
 }
 
 // IsActivated checks whether the computer has been activated.
 // Returns true if the computer is activated, false otherwise.
 func (ta *TurboActivate) IsActivated() (bool, error) {
-	var ret C.HRESULT = C.TA_IsActivated(ta.handle)
+
+	var ret = ta.backend.IsActivated(ta.handle)
 
 	switch ret {
 	case 0x00: // TA_OK
@@ -430,11 +345,8 @@ func (ta *TurboActivate) IsActivated() (bool, error) {
 // this function.
 // Returns true if the date is valid, false if it's not.
 func (ta *TurboActivate) IsDateValid(dateTime string, flags TADateCheckFlags) (bool, error) {
-	var nativeDateTime = getTAStrPtr(dateTime)
-
-	var ret C.HRESULT = C.TA_IsDateValid(ta.handle, nativeDateTime, C.uint32_t(flags))
 
-	C.free(unsafe.Pointer(nativeDateTime))
+	var ret = ta.backend.IsDateValid(ta.handle, dateTime, flags)
 
 	switch ret {
 	case 0x00: // TA_OK
@@ -448,32 +360,21 @@ func (ta *TurboActivate) IsDateValid(dateTime string, flags TADateCheckFlags) (b
 	}
 }
 
+// DateTimeLayout is the "YYYY-MM-DD HH:mm:ss" format of the dates passed to
+// IsDateValid() (and of the date custom license fields in LimeLM).
+const DateTimeLayout = "2006-01-02 15:04:05"
+
+// IsTimeValid is like IsDateValid, but takes a time.Time. The time is converted
+// to UTC and formatted for you, so it can be in any time zone.
+func (ta *TurboActivate) IsTimeValid(t time.Time, flags TADateCheckFlags) (bool, error) {
+	return ta.IsDateValid(t.UTC().Format(DateTimeLayout), flags)
+}
+
 // IsGenuine checks whether the computer is genuinely activated by verifying with the
 // LimeLM servers immediately.
 // Returns an IsGenuineResult value.
 func (ta *TurboActivate) IsGenuine() (IsGenuineResult, error) {
-
-	var ret C.HRESULT = C.TA_IsGenuine(ta.handle)
-
-	switch ret {
-	case 0x00: // TA_OK
-		return IGRGenuine, nil
-
-	case 0x16: // TA_E_FEATURES_CHANGED
-		return IGRGenuineFeaturesChanged, nil
-
-	case 0x04: // TA_E_INET
-		return IGRInternetError, nil
-
-	case 0x01, 0x03, 0x06: // TA_FAIL, TA_E_ACTIVATE, TA_E_REVOKED
-		return IGRNotGenuine, nil
-
-	case 0x11: // TA_E_IN_VM
-		return IGRNotGenuineInVM, nil
-
-	default:
-		return IGRNotGenuine, taHresultToErr(ret, "IsGenuine")
-	}
+	return ta.IsGenuineContext(context.Background())
 }
 
 // IsGenuineEx checks whether the computer is activated, and every "daysBetweenChecks"
@@ -486,47 +387,7 @@ func (ta *TurboActivate) IsGenuine() (IsGenuineResult, error) {
 //                     returning IGRNotGenuine).
 //
 func (ta *TurboActivate) IsGenuineEx(daysBetweenChecks uint32, graceDaysOnInetErr uint32, skipOffline bool, offlineShowInetErr bool) (IsGenuineResult, error) {
-
-	var genOpts C.GENUINE_OPTIONS
-
-	genOpts.nLength = C.uint32_t(unsafe.Sizeof(genOpts))
-
-	if skipOffline {
-		// TA_SKIP_OFFLINE
-		genOpts.flags = 1
-
-		if offlineShowInetErr {
-			// TA_OFFLINE_SHOW_INET_ERR
-			genOpts.flags |= 2
-		}
-	} else {
-		genOpts.flags = 0
-	}
-
-	genOpts.nDaysBetweenChecks = C.uint32_t(daysBetweenChecks)
-	genOpts.nGraceDaysOnInetErr = C.uint32_t(graceDaysOnInetErr)
-
-	var ret C.HRESULT = C.TA_IsGenuineEx(ta.handle, (*C.GENUINE_OPTIONS)(unsafe.Pointer(&genOpts)))
-
-	switch ret {
-	case 0x00: // TA_OK
-		return IGRGenuine, nil
-
-	case 0x16: // TA_E_FEATURES_CHANGED
-		return IGRGenuineFeaturesChanged, nil
-
-	case 0x04, 0x15: // TA_E_INET, TA_E_INET_DELAYED
-		return IGRInternetError, nil
-
-	case 0x01, 0x03, 0x06: // TA_FAIL, TA_E_ACTIVATE, TA_E_REVOKED
-		return IGRNotGenuine, nil
-
-	case 0x11: // TA_E_IN_VM
-		return IGRNotGenuineInVM, nil
-
-	default:
-		return IGRNotGenuine, taHresultToErr(ret, "IsGenuineEx")
-	}
+	return ta.IsGenuineExContext(context.Background(), daysBetweenChecks, graceDaysOnInetErr, skipOffline, offlineShowInetErr)
 }
 
 // GenuineDays gets the number of days until the next time that the IsGenuineEx()
@@ -534,19 +395,14 @@ func (ta *TurboActivate) IsGenuineEx(daysBetweenChecks uint32, graceDaysOnInetEr
 // Returns the number of days remaining and whether the user is in the grace period.
 func (ta *TurboActivate) GenuineDays(daysBetweenChecks uint32, graceDaysOnInetErr uint32) (uint32, bool, error) {
 
-	var daysRemain C.uint32_t
-	var inGrace C.char
-
-	var ret C.HRESULT = C.TA_GenuineDays(ta.handle, C.uint32_t(daysBetweenChecks), C.uint32_t(graceDaysOnInetErr), (*C.uint32_t)(unsafe.Pointer(&daysRemain)), (*C.char)(unsafe.Pointer(&inGrace)))
+	daysRemain, inGracePeriod, ret := ta.backend.GenuineDays(ta.handle, daysBetweenChecks, graceDaysOnInetErr)
 
 	// if != TA_OK
 	if ret != 0x00 {
 		return 0, false, taHresultToErr(ret, "GenuineDays")
 	}
 
-	var inGracePeriod bool = inGrace == 1
-
-	return uint32(daysRemain), inGracePeriod, nil
+	return daysRemain, inGracePeriod, nil
 }
 
 // IsProductKeyValid checks if the product key installed for this product is valid.
@@ -554,7 +410,8 @@ func (ta *TurboActivate) GenuineDays(daysBetweenChecks uint32, graceDaysOnInetEr
 // Use IsActivated()and IsGenuineEx() instead.
 // Returns true if there's a product key that's been saved. False otherwise.
 func (ta *TurboActivate) IsProductKeyValid() (bool, error) {
-	var ret C.HRESULT = C.TA_IsProductKeyValid(ta.handle)
+
+	var ret = ta.backend.IsProductKeyValid(ta.handle)
 
 	switch ret {
 	case 0x00: // TA_OK
@@ -572,11 +429,7 @@ func (ta *TurboActivate) IsProductKeyValid() (bool, error) {
 // connect to the internet.
 func (ta *TurboActivate) SetCustomProxy(proxy string) error {
 
-	var nativeProxy = getTAStrPtr(proxy)
-
-	var ret C.HRESULT = C.TA_SetCustomProxy(nativeProxy)
-
-	C.free(unsafe.Pointer(nativeProxy))
+	var ret = ta.backend.SetCustomProxy(proxy)
 
 	// TA_OK
 	if ret == 0x00 {
@@ -591,13 +444,11 @@ func (ta *TurboActivate) SetCustomProxy(proxy string) error {
 // Returns the number of days remaining. 0 days if the trial has expired. (E.g. 1 day means *at most* 1 day. That is it could be 30 seconds.)
 func (ta *TurboActivate) TrialDaysRemaining(flags TAFlags) (uint32, error) {
 
-	var daysRemain C.uint32_t
-
-	var ret C.HRESULT = C.TA_TrialDaysRemaining(ta.handle, C.uint32_t(flags), (*C.uint32_t)(unsafe.Pointer(&daysRemain)))
+	daysRemain, ret := ta.backend.TrialDaysRemaining(ta.handle, flags)
 
 	// TA_OK
 	if ret == 0x00 {
-		return uint32(daysRemain), nil
+		return daysRemain, nil
 	}
 
 	return 0, taHresultToErr(ret, "TrialDaysRemaining")
@@ -608,27 +459,7 @@ func (ta *TurboActivate) TrialDaysRemaining(flags TAFlags) (uint32, error) {
 // Returns true if there was no error and the trial has not expired. Returns false
 // if there is no trial or it has already expired or there's an error.
 func (ta *TurboActivate) UseTrial(flags TAFlags, extraData string) (bool, error) {
-
-	var ret C.HRESULT
-
-	if extraData == "" {
-		ret = C.TA_UseTrial(ta.handle, C.uint32_t(flags), nil)
-	} else {
-		var nativeExtraData = getTAStrPtr(extraData)
-
-		ret = C.TA_UseTrial(ta.handle, C.uint32_t(flags), nativeExtraData)
-
-		C.free(unsafe.Pointer(nativeExtraData))
-	}
-
-	// TA_OK
-	if ret == 0x00 {
-		return true, nil
-	} else if ret == 0x1E { // TA_E_TRIAL_EXPIRED
-		return false, nil
-	}
-
-	return false, taHresultToErr(ret, "UseTrial")
+	return ta.UseTrialContext(context.Background(), flags, extraData)
 }
 
 // UseTrialVerifiedRequest generates a "verified trial" offline request file.
@@ -637,21 +468,7 @@ func (ta *TurboActivate) UseTrial(flags TAFlags, extraData string) (bool, error)
 // to actually start the trial.
 func (ta *TurboActivate) UseTrialVerifiedRequest(filename string, extraData string) error {
 
-	var ret C.HRESULT
-
-	var nativeFilename = getTAStrPtr(filename)
-
-	if extraData == "" {
-		ret = C.TA_UseTrialVerifiedRequest(ta.handle, nativeFilename, nil)
-	} else {
-		var nativeExtraData = getTAStrPtr(extraData)
-
-		ret = C.TA_UseTrialVerifiedRequest(ta.handle, nativeFilename, nativeExtraData)
-
-		C.free(unsafe.Pointer(nativeExtraData))
-	}
-
-	C.free(unsafe.Pointer(nativeFilename))
+	var ret = ta.backend.UseTrialVerifiedRequest(ta.handle, filename, extraData)
 
 	// TA_OK
 	if ret == 0x00 {
@@ -664,13 +481,7 @@ func (ta *TurboActivate) UseTrialVerifiedRequest(filename string, extraData stri
 // UseTrialVerifiedFromFile uses the "verified trial response" from LimeLM to start the verified trial.
 func (ta *TurboActivate) UseTrialVerifiedFromFile(filename string, flags TAFlags) error {
 
-	var ret C.HRESULT
-
-	var nativeFilename = getTAStrPtr(filename)
-
-	ret = C.TA_UseTrialVerifiedFromFile(ta.handle, nativeFilename, C.uint32_t(flags))
-
-	C.free(unsafe.Pointer(nativeFilename))
+	var ret = ta.backend.UseTrialVerifiedFromFile(ta.handle, filename, flags)
 
 	// TA_OK
 	if ret == 0x00 {
@@ -682,19 +493,7 @@ func (ta *TurboActivate) UseTrialVerifiedFromFile(filename string, flags TAFlags
 
 // ExtendTrial extends the trial using a trial extension created in LimeLM.
 func (ta *TurboActivate) ExtendTrial(trialExtension string, flags TAFlags) error {
-
-	var nativeTrialExtension = getTAStrPtr(trialExtension)
-
-	var ret C.HRESULT = C.TA_ExtendTrial(ta.handle, C.uint32_t(flags), nativeTrialExtension)
-
-	C.free(unsafe.Pointer(nativeTrialExtension))
-
-	// TA_OK
-	if ret == 0x00 {
-		return nil
-	}
-
-	return taHresultToErr(ret, "ExtendTrial")
+	return ta.ExtendTrialContext(context.Background(), trialExtension, flags)
 }
 
 // SetCustomActDataPath function allows you to set a custom folder to store the activation
@@ -713,11 +512,7 @@ func (ta *TurboActivate) ExtendTrial(trialExtension string, flags TAFlags) error
 // must have permission to create, write, and delete files in that directory.
 func (ta *TurboActivate) SetCustomActDataPath(directory string) error {
 
-	var nativeDirectory = getTAStrPtr(directory)
-
-	var ret C.HRESULT = C.TA_SetCustomActDataPath(ta.handle, nativeDirectory)
-
-	C.free(unsafe.Pointer(nativeDirectory))
+	var ret = ta.backend.SetCustomActDataPath(ta.handle, directory)
 
 	// TA_OK
 	if ret == 0x00 {
@@ -0,0 +1,43 @@
+// Copyright 2018 wyDay, LLC. All rights reserved.
+
+package turboactivate_test
+
+import (
+	"testing"
+	"time"
+
+	"golang.wyday.com/turboactivate"
+	"golang.wyday.com/turboactivate/fake"
+)
+
+func TestIsTimeValid(t *testing.T) {
+	b := fake.New()
+
+	var clock = fake.NewManualClock(time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC))
+	b.SetClock(clock.Now)
+
+	ta, err := turboactivate.New(testGUID, turboactivate.WithBackend(b))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer ta.Close()
+
+	// 16:00 in UTC+5 is 11:00 UTC, which has passed (even though 16:00 UTC hasn't)
+	var zone = time.FixedZone("UTC+5", 5*60*60)
+
+	for _, tt := range []struct {
+		t     time.Time
+		valid bool
+	}{
+		{time.Date(2025, 1, 1, 16, 0, 0, 0, zone), false},
+		{time.Date(2025, 1, 1, 18, 0, 0, 0, zone), true},
+		{time.Date(2024, 12, 31, 0, 0, 0, 0, time.UTC), false},
+		{time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC), true},
+	} {
+		if valid, err := ta.IsTimeValid(tt.t, turboactivate.TAHasNotExpired); valid != tt.valid || err != nil {
+			t.Errorf("IsTimeValid(%v) = %v, %v; want %v", tt.t, valid, err, tt.valid)
+		}
+	}
+}
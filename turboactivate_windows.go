@@ -1,6 +1,6 @@
 // Copyright 2018 wyDay, LLC. All rights reserved.
 
-// +build windows
+// +build cgo,windows,!turboactivate_nonative,!turboactivate_dynamic
 
 package turboactivate // import "golang.wyday.com/turboactivate"
 